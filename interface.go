@@ -62,3 +62,18 @@ type Logger interface {
 	// SetLevel changes the logging level to the passed level.
 	SetLevel(level Level)
 }
+
+// StructuredLogger is a Logger that can also write structured logs in which
+// the message is followed by key=value pairs, as done by the DefaultHandler of
+// the v2 module.  The Logger returned by (*Backend).Logger, as well as
+// Disabled, implements it, so a Logger can be type asserted to a
+// StructuredLogger to use it.
+type StructuredLogger interface {
+	Logger
+
+	// LogS writes the message followed by the given alternating keys and
+	// values, formatted as key=value, to log with the given level.  Keys
+	// and values that contain spaces or other special characters are
+	// quoted.
+	LogS(level Level, msg string, keyvals ...interface{})
+}
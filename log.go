@@ -39,10 +39,13 @@ import (
 	"io/ioutil"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // defaultFlags specifies changes to the default logger behavior.  It is set
@@ -258,6 +261,205 @@ func callsite(flag uint32) (string, int) {
 	return file, line
 }
 
+// Copied from log/slog/text_handler.go.
+//
+// needsQuoting returns true if the given strings should be wrapped in quotes.
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			// Quote anything except a backslash that would need
+			// quoting in a JSON string, as well as space and '='.
+			if b != '\\' && (b == ' ' || b == '=' || !safeSet[b]) {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || unicode.IsSpace(r) ||
+			!unicode.IsPrint(r) {
+
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// Copied from encoding/json/tables.go.
+//
+// safeSet holds the value true if the ASCII character with the given array
+// position can be represented inside a JSON string without any further
+// escaping.
+//
+// All values are true except for the ASCII control characters (0-31), the
+// double quote ("), and the backslash character ("\").
+var safeSet = [utf8.RuneSelf]bool{
+	' ':      true,
+	'!':      true,
+	'"':      false,
+	'#':      true,
+	'$':      true,
+	'%':      true,
+	'&':      true,
+	'\'':     true,
+	'(':      true,
+	')':      true,
+	'*':      true,
+	'+':      true,
+	',':      true,
+	'-':      true,
+	'.':      true,
+	'/':      true,
+	'0':      true,
+	'1':      true,
+	'2':      true,
+	'3':      true,
+	'4':      true,
+	'5':      true,
+	'6':      true,
+	'7':      true,
+	'8':      true,
+	'9':      true,
+	':':      true,
+	';':      true,
+	'<':      true,
+	'=':      true,
+	'>':      true,
+	'?':      true,
+	'@':      true,
+	'A':      true,
+	'B':      true,
+	'C':      true,
+	'D':      true,
+	'E':      true,
+	'F':      true,
+	'G':      true,
+	'H':      true,
+	'I':      true,
+	'J':      true,
+	'K':      true,
+	'L':      true,
+	'M':      true,
+	'N':      true,
+	'O':      true,
+	'P':      true,
+	'Q':      true,
+	'R':      true,
+	'S':      true,
+	'T':      true,
+	'U':      true,
+	'V':      true,
+	'W':      true,
+	'X':      true,
+	'Y':      true,
+	'Z':      true,
+	'[':      true,
+	'\\':     false,
+	']':      true,
+	'^':      true,
+	'_':      true,
+	'`':      true,
+	'a':      true,
+	'b':      true,
+	'c':      true,
+	'd':      true,
+	'e':      true,
+	'f':      true,
+	'g':      true,
+	'h':      true,
+	'i':      true,
+	'j':      true,
+	'k':      true,
+	'l':      true,
+	'm':      true,
+	'n':      true,
+	'o':      true,
+	'p':      true,
+	'q':      true,
+	'r':      true,
+	's':      true,
+	't':      true,
+	'u':      true,
+	'v':      true,
+	'w':      true,
+	'x':      true,
+	'y':      true,
+	'z':      true,
+	'{':      true,
+	'|':      true,
+	'}':      true,
+	'~':      true,
+	'\u007f': true,
+}
+
+// appendString appends the given string to the buffer, quoted if it contains
+// any characters that would make a key-value pair ambiguous.
+func appendString(buf *[]byte, s string) {
+	if needsQuoting(s) {
+		*buf = strconv.AppendQuote(*buf, s)
+	} else {
+		*buf = append(*buf, s...)
+	}
+}
+
+// appendValue appends the given value to the buffer. Strings are appended as
+// is, while any other value is formatted with the %+v verb, before quoting it
+// if needed.
+func appendValue(buf *[]byte, v interface{}) {
+	defer func() {
+		// Recovery in case of nil pointer dereferences.
+		if r := recover(); r != nil {
+			// Catch any panics that are most likely due to nil
+			// pointers.
+			appendString(buf, fmt.Sprintf("!PANIC: %v", r))
+		}
+	}()
+
+	if s, ok := v.(string); ok {
+		appendString(buf, s)
+		return
+	}
+
+	appendString(buf, fmt.Sprintf("%+v", v))
+}
+
+// badKey is the key used for a value in a list of key-value pairs that isn't
+// preceded by a string key.
+const badKey = "!BADKEY"
+
+// appendKeyVals appends the given alternating keys and values to the buffer as
+// space separated key=value pairs, quoting keys and values in the same way as
+// the DefaultHandler of the v2 module. A value that isn't preceded by a string
+// key, including a trailing key without a value, is logged with the key
+// !BADKEY.
+func appendKeyVals(buf *[]byte, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i++ {
+		key, ok := keyvals[i].(string)
+		var value interface{}
+		switch {
+		case !ok:
+			key, value = badKey, keyvals[i]
+
+		case i+1 == len(keyvals):
+			key, value = badKey, key
+
+		default:
+			i++
+			value = keyvals[i]
+		}
+
+		*buf = append(*buf, ' ')
+		appendString(buf, key)
+		*buf = append(*buf, '=')
+		appendValue(buf, value)
+	}
+}
+
 // print outputs a log message to the writer associated with the backend after
 // creating a prefix for the given level and tag according to the formatHeader
 // function and formatting the provided arguments using the default formatting
@@ -312,6 +514,35 @@ func (b *Backend) printf(lvl, tag string, format string, args ...interface{}) {
 	recycleBuffer(bytebuf)
 }
 
+// prints outputs a log message to the writer associated with the backend after
+// creating a prefix for the given level and tag according to the formatHeader
+// function and appending the provided key-value pairs to the message according
+// to the appendKeyVals function.
+func (b *Backend) prints(lvl, tag string, msg string,
+	keyvals ...interface{}) {
+
+	t := time.Now() // get as early as possible
+
+	bytebuf := buffer()
+
+	var file string
+	var line int
+	if b.flag&(Lshortfile|Llongfile) != 0 {
+		file, line = callsite(b.flag)
+	}
+
+	formatHeader(bytebuf, t, lvl, tag, file, line)
+	*bytebuf = append(*bytebuf, msg...)
+	appendKeyVals(bytebuf, keyvals)
+	*bytebuf = append(*bytebuf, '\n')
+
+	b.mu.Lock()
+	b.w.Write(*bytebuf)
+	b.mu.Unlock()
+
+	recycleBuffer(bytebuf)
+}
+
 // Logger returns a new logger for a particular subsystem that writes to the
 // Backend b.  A tag describes the subsystem and is included in all log
 // messages.  The logger uses the info verbosity level by default.
@@ -326,6 +557,9 @@ type slog struct {
 	b   *Backend
 }
 
+// A compile-time check to ensure that slog implements StructuredLogger.
+var _ StructuredLogger = (*slog)(nil)
+
 // Trace formats message using the default formats for its operands, prepends
 // the prefix as necessary, and writes to log with LevelTrace.
 //
@@ -458,6 +692,17 @@ func (l *slog) Criticalf(format string, args ...interface{}) {
 	}
 }
 
+// LogS writes the message followed by the given key-value pairs, formatted as
+// key=value, to log with the given level.
+//
+// This is part of the StructuredLogger interface implementation.
+func (l *slog) LogS(level Level, msg string, keyvals ...interface{}) {
+	lvl := l.Level()
+	if level < LevelOff && lvl <= level {
+		l.b.prints(level.String(), l.tag, msg, keyvals...)
+	}
+}
+
 // Level returns the current logging level
 //
 // This is part of the Logger interface implementation.
@@ -0,0 +1,65 @@
+package btclog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestLogS tests that the key-value pairs of LogS are quoted in the same way as
+// by the DefaultHandler of the v2 module. The expected lines are the output of
+// the v2 DefaultHandler for the same calls.
+func TestLogS(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewBackend(&buf).Logger("PEER").(StructuredLogger)
+	log.SetLevel(LevelInfo)
+
+	log.LogS(LevelInfo, "Spaces", "key with space", "a b", "plain", "ab")
+	log.LogS(LevelInfo, "Equals", "k", "a=b", "a=b", 1)
+	log.LogS(LevelInfo, "Quotes", "q", `say "hi"`, "empty", "")
+	log.LogS(LevelInfo, "Invalid UTF-8", "bin", "\xff\xfe", "uni", "héllo")
+	log.LogS(LevelInfo, "Odd", "k", 1, "dangling")
+	log.LogS(LevelInfo, "Bad key", 5, "v", "n", -2.5, "b", true)
+
+	// Records below the level of the logger, or at LevelOff, are dropped.
+	log.LogS(LevelDebug, "Dropped")
+	log.LogS(LevelOff, "Dropped")
+	log.SetLevel(LevelOff)
+	log.LogS(LevelCritical, "Dropped")
+
+	expected := []string{
+		`[INF] PEER: Spaces "key with space"="a b" plain=ab`,
+		`[INF] PEER: Equals k="a=b" "a=b"=1`,
+		`[INF] PEER: Quotes q="say \"hi\"" empty=""`,
+		`[INF] PEER: Invalid UTF-8 bin="\xff\xfe" uni=héllo`,
+		`[INF] PEER: Odd k=1 !BADKEY=dangling`,
+		`[INF] PEER: Bad key !BADKEY=5 v=n !BADKEY=-2.5 b=true`,
+	}
+
+	// Strip the timestamps that the Backend always writes.
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got:\n%s", len(expected),
+			buf.String())
+	}
+	for i, line := range lines {
+		line = line[strings.Index(line, " [")+1:]
+		if line != expected[i] {
+			t.Fatalf("Expected %q, got %q", expected[i], line)
+		}
+	}
+}
+
+// TestLogSDisabled tests that the Disabled Logger implements StructuredLogger
+// and is switched off.
+func TestLogSDisabled(t *testing.T) {
+	log, ok := Disabled.(StructuredLogger)
+	if !ok {
+		t.Fatalf("Disabled does not implement StructuredLogger")
+	}
+
+	log.LogS(LevelCritical, "Dropped", "k", 1)
+	if log.Level() != LevelOff {
+		t.Fatalf("Expected LevelOff, got %v", log.Level())
+	}
+}
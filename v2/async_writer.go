@@ -0,0 +1,415 @@
+package btclog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// ErrAsyncWriterClosed is returned when writing to a closed AsyncWriter.
+var ErrAsyncWriterClosed = errors.New("async writer is closed")
+
+// OverflowPolicy defines what an AsyncWriter does with a log line if its queue
+// is full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock blocks the caller until there is space in the queue.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest drops the log line that is being written.
+	OverflowDropNewest
+
+	// OverflowDropOldest drops the oldest queued log line to make space
+	// for the one that is being written.
+	OverflowDropOldest
+
+	// OverflowDropBelowLevel drops the log line that is being written if
+	// its level is below the level set with WithAsyncDropLevel. Log lines
+	// at or above that level block the caller until there is space in the
+	// queue.
+	OverflowDropBelowLevel
+)
+
+// String returns a human-readable name for the policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropBelowLevel:
+		return "drop-below-level"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(p))
+	}
+}
+
+// AsyncOption is the signature of a functional option that can be used to
+// modify the behaviour of an AsyncWriter.
+type AsyncOption func(*asyncOpts)
+
+// asyncOpts holds options that can be modified by an AsyncOption.
+type asyncOpts struct {
+	// queueSize is the maximum number of log lines that may be queued.
+	queueSize int
+
+	// policy defines what to do when the queue is full.
+	policy OverflowPolicy
+
+	// dropLevel is the level below which log lines are dropped when the
+	// queue is full and the policy is OverflowDropBelowLevel.
+	dropLevel btclog.Level
+
+	// reportInterval is the interval at which the number of dropped log
+	// lines is reported.
+	reportInterval time.Duration
+
+	// reportDropped is called with the number of log lines dropped since
+	// the last report.
+	reportDropped func(w io.Writer, dropped uint64)
+}
+
+// defaultAsyncOpts constructs an asyncOpts with default settings.
+func defaultAsyncOpts() *asyncOpts {
+	return &asyncOpts{
+		queueSize:      1024,
+		policy:         OverflowBlock,
+		dropLevel:      LevelWarn,
+		reportInterval: 10 * time.Second,
+		reportDropped:  writeDroppedReport,
+	}
+}
+
+// WithAsyncQueueSize sets the maximum number of log lines that can be queued
+// before the overflow policy is applied. A size below one is treated as one.
+func WithAsyncQueueSize(size int) AsyncOption {
+	return func(opts *asyncOpts) {
+		opts.queueSize = size
+	}
+}
+
+// WithOverflowPolicy sets the policy that is applied when the queue is full.
+func WithOverflowPolicy(policy OverflowPolicy) AsyncOption {
+	return func(opts *asyncOpts) {
+		opts.policy = policy
+	}
+}
+
+// WithAsyncDropLevel sets the level below which log lines are dropped when
+// the queue is full and the OverflowDropBelowLevel policy is used.
+func WithAsyncDropLevel(level btclog.Level) AsyncOption {
+	return func(opts *asyncOpts) {
+		opts.dropLevel = level
+	}
+}
+
+// WithDropReportInterval sets the interval at which the number of dropped log
+// lines is reported. If the interval is not positive, the number is not
+// reported periodically but only when the AsyncWriter is flushed or closed.
+func WithDropReportInterval(interval time.Duration) AsyncOption {
+	return func(opts *asyncOpts) {
+		opts.reportInterval = interval
+	}
+}
+
+// WithDropReporter can be used to overwrite how the number of dropped log
+// lines is reported. The call-back is given the underlying writer and the
+// number of log lines dropped since the last report. By default, a WRN line in
+// the DefaultHandler format is written.
+func WithDropReporter(fn func(w io.Writer, dropped uint64)) AsyncOption {
+	return func(opts *asyncOpts) {
+		opts.reportDropped = fn
+	}
+}
+
+// AsyncWriter is an io.Writer that queues log lines and writes them to an
+// underlying writer from a separate goroutine so that a slow writer doesn't
+// stall the callers. It can be used as the writer of both the DefaultHandler
+// and a v1 Backend.
+//
+// The level of each log line, which is needed by the OverflowDropBelowLevel
+// policy, is determined from the "[LVL]" header of the text format or the
+// "level" field of the JSON format.
+type AsyncWriter struct {
+	w    io.Writer
+	opts *asyncOpts
+
+	queue chan []byte
+
+	// dropped is the total number of dropped log lines.
+	dropped atomic.Uint64
+
+	// err is the first error returned by the underlying writer.
+	errMu sync.Mutex
+	err   error
+
+	// mu guards closed and ensures that no log line is queued once the
+	// writer is closed.
+	mu     sync.RWMutex
+	closed bool
+
+	flushReq chan chan struct{}
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// A compile-time check to ensure that AsyncWriter implements io.WriteCloser.
+var _ io.WriteCloser = (*AsyncWriter)(nil)
+
+// NewAsyncWriter creates a new AsyncWriter that writes to w and starts its
+// writer goroutine. Close must be called to stop it.
+func NewAsyncWriter(w io.Writer, options ...AsyncOption) *AsyncWriter {
+	opts := defaultAsyncOpts()
+	for _, o := range options {
+		o(opts)
+	}
+
+	// An unbuffered queue would turn every write into a hand-off to the
+	// writer goroutine, so at least one log line can always be queued.
+	if opts.queueSize < 1 {
+		opts.queueSize = 1
+	}
+
+	a := &AsyncWriter{
+		w:        w,
+		opts:     opts,
+		queue:    make(chan []byte, opts.queueSize),
+		flushReq: make(chan chan struct{}),
+		quit:     make(chan struct{}),
+	}
+
+	a.wg.Add(1)
+	go a.writer()
+
+	return a
+}
+
+// Write queues a copy of p to be written to the underlying writer. If the
+// queue is full, the overflow policy is applied. A dropped log line is not
+// reported as an error.
+//
+// NOTE: This is part of the io.Writer interface.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	// The caller may re-use p once we return, so we need our own copy.
+	line := append([]byte(nil), p...)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+
+	// Fast path: there is space in the queue.
+	select {
+	case a.queue <- line:
+		return len(p), nil
+	default:
+	}
+
+	switch a.opts.policy {
+	case OverflowDropNewest:
+		a.dropped.Add(1)
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case a.queue <- line:
+				return len(p), nil
+			default:
+			}
+
+			select {
+			case <-a.queue:
+				a.dropped.Add(1)
+			default:
+			}
+		}
+
+	case OverflowDropBelowLevel:
+		level, ok := lineLevel(line)
+		if ok && level < a.opts.dropLevel {
+			a.dropped.Add(1)
+			break
+		}
+		a.queue <- line
+
+	default:
+		a.queue <- line
+	}
+
+	return len(p), nil
+}
+
+// Flush blocks until all log lines queued before the call have been written to
+// the underlying writer. The first error returned by the underlying writer, if
+// any, is returned.
+func (a *AsyncWriter) Flush() error {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return ErrAsyncWriterClosed
+	}
+
+	done := make(chan struct{})
+	a.flushReq <- done
+	a.mu.RUnlock()
+
+	<-done
+
+	return a.writeErr()
+}
+
+// Close writes all queued log lines to the underlying writer and stops the
+// writer goroutine. The underlying writer itself is not closed. The first error
+// returned by the underlying writer, if any, is returned.
+//
+// NOTE: This is part of the io.Closer interface.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrAsyncWriterClosed
+	}
+	a.closed = true
+	a.mu.Unlock()
+
+	close(a.quit)
+	a.wg.Wait()
+
+	return a.writeErr()
+}
+
+// Dropped returns the total number of log lines that have been dropped due to
+// the overflow policy.
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// writer writes queued log lines to the underlying writer and periodically
+// reports the number of dropped log lines until the AsyncWriter is closed.
+//
+// NOTE: This MUST be run as a goroutine.
+func (a *AsyncWriter) writer() {
+	defer a.wg.Done()
+
+	// A nil channel is never ready, so no periodic reports are done if
+	// there is no interval.
+	var tick <-chan time.Time
+	if a.opts.reportInterval > 0 {
+		ticker := time.NewTicker(a.opts.reportInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	var reported uint64
+	report := func() {
+		dropped := a.dropped.Load()
+		if dropped > reported {
+			a.opts.reportDropped(a.w, dropped-reported)
+			reported = dropped
+		}
+	}
+
+	for {
+		select {
+		case line := <-a.queue:
+			a.write(line)
+
+		case done := <-a.flushReq:
+			a.drain()
+			report()
+			close(done)
+
+		case <-tick:
+			report()
+
+		case <-a.quit:
+			a.drain()
+			report()
+			return
+		}
+	}
+}
+
+// drain writes all currently queued log lines to the underlying writer.
+func (a *AsyncWriter) drain() {
+	for {
+		select {
+		case line := <-a.queue:
+			a.write(line)
+		default:
+			return
+		}
+	}
+}
+
+// write writes a single log line to the underlying writer and records the
+// first error encountered.
+func (a *AsyncWriter) write(line []byte) {
+	if _, err := a.w.Write(line); err != nil {
+		a.errMu.Lock()
+		if a.err == nil {
+			a.err = err
+		}
+		a.errMu.Unlock()
+	}
+}
+
+// writeErr returns the first error returned by the underlying writer.
+func (a *AsyncWriter) writeErr() error {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+
+	return a.err
+}
+
+// writeDroppedReport is the default drop reporter. It writes a WRN log line in
+// the DefaultHandler format to the given writer.
+func writeDroppedReport(w io.Writer, dropped uint64) {
+	buf := newBuffer()
+	defer buf.free()
+
+	writeTimestamp(buf, time.Now())
+	buf.writeString(fmt.Sprintf("[%s] BTCL: Dropped %d log lines due "+
+		"to a full queue\n", LevelWarn, dropped))
+
+	_, _ = w.Write(*buf)
+}
+
+// levelFieldPrefix precedes the level in a JSON log line.
+var levelFieldPrefix = []byte(`"level":"`)
+
+// lineLevel determines the level of a formatted log line from its "[LVL]"
+// header or, for JSON log lines, from its "level" field.
+func lineLevel(line []byte) (btclog.Level, bool) {
+	// The header may be preceded by a timestamp and both may be wrapped in
+	// terminal escape sequences, so look for the first bracketed level.
+	for i := 0; i+4 < len(line); i++ {
+		if line[i] != '[' || line[i+4] != ']' {
+			continue
+		}
+		if level, ok := LevelFromString(string(line[i+1 : i+4])); ok {
+			return level, true
+		}
+	}
+
+	if i := bytes.Index(line, levelFieldPrefix); i >= 0 {
+		start := i + len(levelFieldPrefix)
+		if end := start + 3; end < len(line) && line[end] == '"' {
+			return LevelFromString(string(line[start:end]))
+		}
+	}
+
+	return LevelInfo, false
+}
@@ -0,0 +1,240 @@
+package btclog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// gatedWriter is an io.Writer that blocks each write until the gate is
+// opened. It signals on entered whenever a write starts.
+type gatedWriter struct {
+	entered chan struct{}
+	gate    chan struct{}
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// newGatedWriter creates a new gatedWriter with a closed gate.
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{
+		entered: make(chan struct{}, 100),
+		gate:    make(chan struct{}),
+	}
+}
+
+// Write blocks until the gate is opened and then records p.
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	g.entered <- struct{}{}
+	<-g.gate
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.buf.Write(p)
+}
+
+// String returns everything written so far.
+func (g *gatedWriter) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.buf.String()
+}
+
+// TestAsyncWriterOverflow tests that each overflow policy drops the expected
+// log lines once the queue is full.
+func TestAsyncWriterOverflow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     []AsyncOption
+		lines    []string
+		expected string
+	}{
+		{
+			name: "drop newest",
+			opts: []AsyncOption{
+				WithOverflowPolicy(OverflowDropNewest),
+			},
+			lines:    []string{"1", "2", "3", "4"},
+			expected: "1\n2\n3\n",
+		},
+		{
+			name: "drop oldest",
+			opts: []AsyncOption{
+				WithOverflowPolicy(OverflowDropOldest),
+			},
+			lines:    []string{"1", "2", "3", "4"},
+			expected: "1\n3\n4\n",
+		},
+		{
+			name: "drop below level",
+			opts: []AsyncOption{
+				WithOverflowPolicy(OverflowDropBelowLevel),
+				WithAsyncDropLevel(LevelWarn),
+			},
+			lines: []string{
+				"[INF]: 1", "[INF]: 2", "[INF]: 3",
+				"[DBG]: 4", "[WRN]: 5",
+			},
+			expected: "[INF]: 1\n[INF]: 2\n[INF]: 3\n" +
+				"[WRN]: 5\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newGatedWriter()
+
+			var reported uint64
+			opts := append([]AsyncOption{
+				WithAsyncQueueSize(2),
+				WithDropReporter(func(_ io.Writer, n uint64) {
+					reported += n
+				}),
+			}, test.opts...)
+			a := NewAsyncWriter(w, opts...)
+
+			// Wait for the first line to be picked up by the
+			// writer goroutine so that the remaining lines fill
+			// up the queue.
+			fmt.Fprintln(a, test.lines[0])
+			<-w.entered
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for _, line := range test.lines[1:] {
+					fmt.Fprintln(a, line)
+				}
+			}()
+
+			// Only open the gate once a line has been dropped
+			// since the drop below level policy blocks on the last
+			// line until the queue has space.
+			for a.Dropped() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			close(w.gate)
+			<-done
+
+			if err := a.Close(); err != nil {
+				t.Fatalf("Unable to close: %v", err)
+			}
+
+			if w.String() != test.expected {
+				t.Fatalf("Expected %q, got %q", test.expected,
+					w.String())
+			}
+			if a.Dropped() != 1 || reported != 1 {
+				t.Fatalf("Expected 1 dropped line, got %d "+
+					"(reported %d)", a.Dropped(), reported)
+			}
+		})
+	}
+}
+
+// TestAsyncWriterFlush tests that Flush and Close write out all queued log
+// lines and that the writer can't be used after it is closed.
+func TestAsyncWriterFlush(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	a := NewAsyncWriter(&buf)
+
+	log := NewSLogger(NewDefaultHandler(a, WithNoTimestamp()))
+	log.Info("Line 1")
+	log.Info("Line 2")
+
+	if err := a.Flush(); err != nil {
+		t.Fatalf("Unable to flush: %v", err)
+	}
+	if buf.String() != "[INF]: Line 1\n[INF]: Line 2\n" {
+		t.Fatalf("Unexpected output after flush: %q", buf.String())
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Unable to close: %v", err)
+	}
+	if _, err := a.Write([]byte("closed")); err != ErrAsyncWriterClosed {
+		t.Fatalf("Expected ErrAsyncWriterClosed, got %v", err)
+	}
+}
+
+// TestAsyncWriterInvalidOptions tests that a non-positive queue size and
+// report interval don't crash the AsyncWriter and that dropped log lines are
+// still reported when it is closed.
+func TestAsyncWriterInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	var reported uint64
+	g := newGatedWriter()
+	a := NewAsyncWriter(
+		g, WithAsyncQueueSize(-1), WithDropReportInterval(0),
+		WithOverflowPolicy(OverflowDropNewest),
+		WithDropReporter(func(_ io.Writer, dropped uint64) {
+			reported += dropped
+		}),
+	)
+
+	// The first line is taken by the writer goroutine, the second one
+	// fills the queue and the third one is dropped.
+	if _, err := a.Write([]byte("Line 1\n")); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+	<-g.entered
+	for _, line := range []string{"Line 2\n", "Line 3\n"} {
+		if _, err := a.Write([]byte(line)); err != nil {
+			t.Fatalf("Unable to write: %v", err)
+		}
+	}
+
+	close(g.gate)
+	if err := a.Close(); err != nil {
+		t.Fatalf("Unable to close: %v", err)
+	}
+
+	if g.String() != "Line 1\nLine 2\n" {
+		t.Fatalf("Unexpected output: %q", g.String())
+	}
+	if reported != 1 {
+		t.Fatalf("Expected 1 dropped line to be reported, got %d",
+			reported)
+	}
+}
+
+// TestLineLevel tests that the level of a formatted log line is detected for
+// the different output formats.
+func TestLineLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		line  string
+		level btclog.Level
+		ok    bool
+	}{
+		{"2024-10-03 13:34:17.123 [DBG] PEER: msg", LevelDebug, true},
+		{"\x1b[2m2024-10-03\x1b[0m \x1b[31m[ERR]\x1b[0m: m", LevelError,
+			true},
+		{`{"time":"2024-10-03","level":"TRC","msg":"m"}`, LevelTrace,
+			true},
+		{"no level [here]", LevelInfo, false},
+	}
+
+	for _, test := range tests {
+		level, ok := lineLevel([]byte(test.line))
+		if level != test.level || ok != test.ok {
+			t.Fatalf("%q: expected (%s, %v), got (%s, %v)",
+				test.line, test.level, test.ok, level, ok)
+		}
+	}
+}
@@ -3,7 +3,13 @@ package btclog
 import (
 	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
+
+	"github.com/btcsuite/btclog"
 )
 
 // Hex is a convenience function for a hex-encoded log attributes.
@@ -13,10 +19,178 @@ func Hex(key string, value []byte) slog.Attr {
 	return slog.String(key, h)
 }
 
+// pubKeyAbbrevLen is the number of hex characters at the start and at the end
+// of a public key that are kept by PubKey.
+const pubKeyAbbrevLen = 8
+
+// Hash is a convenience function for a 32-byte hash attribute, such as a txid
+// or a block hash. The hash is hex-encoded in reverse byte order, which is how
+// Bitcoin hashes are usually displayed.
+func Hash(key string, hash [32]byte) slog.Attr {
+	return slog.String(key, reversedHex(hash))
+}
+
+// Amount is a convenience function for an amount attribute. The amount is given
+// in satoshis and is logged in BTC with a fixed eight decimals, for example
+// 0.00100000.
+func Amount(key string, sats int64) slog.Attr {
+	var sign string
+	abs := uint64(sats)
+	if sats < 0 {
+		sign = "-"
+		abs = -abs
+	}
+
+	const satsPerBTC = 100_000_000
+	btc := fmt.Sprintf("%s%d.%08d", sign, abs/satsPerBTC, abs%satsPerBTC)
+
+	return slog.String(key, btc)
+}
+
+// OutPoint is a convenience function for an outpoint attribute. It is logged as
+// txid:index with the txid in display order, as for Hash.
+func OutPoint(key string, txid [32]byte, index uint32) slog.Attr {
+	op := reversedHex(txid) + ":" + strconv.FormatUint(uint64(index), 10)
+
+	return slog.String(key, op)
+}
+
+// ShortChanID is a convenience function for a short channel ID attribute. The
+// ID is logged in its human-readable form, blockxtxxoutput, for example
+// 539268x845x1.
+func ShortChanID(key string, chanID uint64) slog.Attr {
+	scid := fmt.Sprintf("%dx%dx%d", chanID>>40, chanID>>16&0xffffff,
+		chanID&0xffff)
+
+	return slog.String(key, scid)
+}
+
+// PubKey is a convenience function for a public key attribute. Only the first
+// and the last eight hex characters of the key are logged, which is enough to
+// identify it in the logs.
+func PubKey(key string, pubKey []byte) slog.Attr {
+	return AbbrevHex(key, pubKey, pubKeyAbbrevLen)
+}
+
+// AbbrevHex is a convenience function for an abbreviated hex-encoded attribute.
+// Only the first and the last n hex characters of the value are logged,
+// separated by "...". Values that are too short to be abbreviated are logged
+// in full.
+func AbbrevHex(key string, value []byte, n int) slog.Attr {
+	h := hex.EncodeToString(value)
+	if n >= 0 && len(h) > 2*n+3 {
+		h = h[:n] + "..." + h[len(h)-n:]
+	}
+
+	return slog.String(key, h)
+}
+
+// lazyValue is a slog.LogValuer that computes the value of an attribute by
+// calling a function.
+type lazyValue func() any
+
+// LogValue returns the value computed by the function.
+//
+// NOTE: This is part of the slog.LogValuer interface.
+func (f lazyValue) LogValue() slog.Value {
+	return slog.AnyValue(f())
+}
+
+// Lazy is a convenience function for an attribute whose value is expensive to
+// compute, such as a spew dump of a message. The function is only called once
+// a handler resolves the value, which it only does for records that are
+// actually logged. The function may therefore be called multiple times, or
+// never, for a single log call.
+//
+// Usage:
+//
+//	log.TraceS(ctx, "Received message", log.Lazy("msg", func() any {
+//		return spew.Sdump(msg)
+//	}))
+func Lazy(key string, fn func() any) slog.Attr {
+	return slog.Any(key, lazyValue(fn))
+}
+
+// lazySprintf is a slog.LogValuer that formats its arguments according to a
+// format specifier once it is resolved.
+type lazySprintf struct {
+	format string
+	args   []any
+}
+
+// LogValue returns the formatted string.
+//
+// NOTE: This is part of the slog.LogValuer interface.
+func (l lazySprintf) LogValue() slog.Value {
+	return slog.StringValue(fmt.Sprintf(l.format, l.args...))
+}
+
+// LazySprintf is a convenience function for an attribute whose value is
+// formatted according to a format specifier, as by fmt.Sprintf. The formatting
+// is only done once a handler resolves the value, which it only does for
+// records that are actually logged.
+func LazySprintf(key, format string, args ...any) slog.Attr {
+	return slog.Any(key, lazySprintf{format: format, args: args})
+}
+
+// reversedHex returns the hex encoding of the given hash in reverse byte
+// order.
+func reversedHex(hash [32]byte) string {
+	for i := 0; i < len(hash)/2; i++ {
+		hash[i], hash[len(hash)-1-i] = hash[len(hash)-1-i], hash[i]
+	}
+
+	return hex.EncodeToString(hash[:])
+}
+
 type attrsKey struct{}
 
+// badKey is the key of attributes that were given without a valid key, as used
+// by the slog package.
+const badKey = "!BADKEY"
+
+// ErrMalformedCtxAttrs is returned by ValidateCtxAttrs if the given attributes
+// are not valid alternating keys and values.
+var ErrMalformedCtxAttrs = errors.New("malformed context attributes")
+
+// ValidateCtxAttrs checks that the given attributes, as passed to WithCtx, are
+// well-formed: each key must be a string followed by a value, or a slog.Attr.
+// An error wrapping ErrMalformedCtxAttrs that describes the first malformed
+// attribute is returned otherwise. It can be used to check attributes that are
+// built at run-time before they are passed to WithCtx.
+func ValidateCtxAttrs(attrs ...any) error {
+	for i := 0; i < len(attrs); i++ {
+		switch key := attrs[i].(type) {
+		case slog.Attr:
+
+		case string:
+			if i+1 == len(attrs) {
+				return fmt.Errorf("%w: missing value for "+
+					"key %q", ErrMalformedCtxAttrs, key)
+			}
+			i++
+
+		default:
+			return fmt.Errorf("%w: key %v at index %d is of type "+
+				"%T, not string", ErrMalformedCtxAttrs, key, i,
+				key)
+		}
+	}
+
+	return nil
+}
+
 // WithCtx returns a copy of the context with which the logging attributes are
-// associated.
+// associated. The attributes are given as alternating keys and values, as for
+// the structured logging methods, and may also contain slog.Attr values. If a
+// key is already associated with the context, its value is replaced.
+//
+// As done by the slog package, malformed attributes are kept with the !BADKEY
+// key so that the mistake shows up in the logs: a key that is not a string is
+// kept as the value of a !BADKEY attribute, as is a last key that has no value.
+// To catch such mistakes instead, either validate the attributes with
+// ValidateCtxAttrs first or use WithCtxAttrs, whose typed attributes can't be
+// malformed.
 //
 // Usage:
 //
@@ -24,14 +198,208 @@ type attrsKey struct{}
 //	...
 //	log.Info(ctx, "Height processed") // Will contain attribute: height=1234
 func WithCtx(ctx context.Context, attrs ...any) context.Context {
-	return context.WithValue(ctx, attrsKey{}, mergeAttrs(ctx, attrs))
+	return WithCtxAttrs(ctx, argsToAttrs(attrs)...)
+}
+
+// WithCtxAttrs returns a copy of the context with which the given attributes
+// are associated. If a key is already associated with the context, its value
+// is replaced.
+func WithCtxAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	ctxAttrs := ctxAttrs(ctx)
+
+	merged := make([]slog.Attr, len(ctxAttrs), len(ctxAttrs)+len(attrs))
+	copy(merged, ctxAttrs)
+
+	for _, a := range attrs {
+		i := attrIndex(merged, a.Key)
+		if i < 0 {
+			merged = append(merged, a)
+			continue
+		}
+
+		merged[i] = a
+	}
+
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// WithoutCtx returns a copy of the context from which the logging attributes
+// with the given keys have been removed.
+func WithoutCtx(ctx context.Context, keys ...string) context.Context {
+	ctxAttrs := ctxAttrs(ctx)
+
+	remaining := make([]slog.Attr, 0, len(ctxAttrs))
+	for _, a := range ctxAttrs {
+		if !slices.Contains(keys, a.Key) {
+			remaining = append(remaining, a)
+		}
+	}
+
+	return context.WithValue(ctx, attrsKey{}, remaining)
+}
+
+// ctxAttrs returns the attributes associated with the context. The returned
+// slice must not be modified.
+func ctxAttrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr) // We know the type.
+
+	return attrs
+}
+
+// attrIndex returns the index of the attribute with the given key or -1 if
+// there is none. Attributes with an empty key, such as inlined groups, and
+// malformed attributes are never matched.
+func attrIndex(attrs []slog.Attr, key string) int {
+	if key == "" || key == badKey {
+		return -1
+	}
+
+	for i, a := range attrs {
+		if a.Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// argsToAttrs converts the given alternating keys and values to attributes. A
+// key that is not a string or slog.Attr, or a last key that has no value, is
+// converted to an attribute with the !BADKEY key, in the same way as done by
+// the slog package.
+func argsToAttrs(args []any) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(args)/2)
+	for i := 0; i < len(args); i++ {
+		switch key := args[i].(type) {
+		case slog.Attr:
+			attrs = append(attrs, key)
+
+		case string:
+			if i+1 == len(args) {
+				attrs = append(attrs, slog.String(badKey, key))
+				continue
+			}
+			i++
+			attrs = append(attrs, slog.Any(key, args[i]))
+
+		default:
+			attrs = append(attrs, slog.Any(badKey, key))
+		}
+	}
+
+	return attrs
 }
 
 // mergeAttrs returns the attributes from the context merged with the provided
-// attributes.
+// attributes. Context attributes whose key is also among the provided
+// attributes are left out so that the provided value takes precedence.
 func mergeAttrs(ctx context.Context, attrs []any) []any {
-	resp, _ := ctx.Value(attrsKey{}).([]any) // We know the type.
-	resp = append(resp, attrs...)
+	ctxAttrs := ctxAttrs(ctx)
+	if len(ctxAttrs) == 0 {
+		return attrs
+	}
+
+	resp := make([]any, 0, len(ctxAttrs)+len(attrs))
+	for _, a := range ctxAttrs {
+		if !hasArgKey(attrs, a.Key) {
+			resp = append(resp, a)
+		}
+	}
+
+	return append(resp, attrs...)
+}
+
+// hasArgKey returns true if the given alternating keys and values contain the
+// given key, either as a string key or as the key of a slog.Attr. Empty and
+// !BADKEY keys never match.
+func hasArgKey(args []any, key string) bool {
+	if key == "" || key == badKey {
+		return false
+	}
+
+	for i := 0; i < len(args); i++ {
+		switch k := args[i].(type) {
+		case slog.Attr:
+			if k.Key == key {
+				return true
+			}
+
+		case string:
+			if k == key && i+1 < len(args) {
+				return true
+			}
+			i++
+		}
+	}
+
+	return false
+}
+
+type levelKey struct{}
+
+// WithCtxLevel returns a copy of the context with which a minimum logging
+// level is associated. Records that are logged with the context are handled by
+// the DefaultHandler and the JSONHandler if they are at or above either the
+// handler's level or the context's level. This way, for example, trace logs
+// can be enabled for a single request or peer without changing the level of
+// the whole sub-system. The context level can only enable more records, it
+// never disables records that the handler's level allows. A handler that is
+// set to LevelOff stays off regardless of the context level.
+//
+// Usage:
+//
+//	ctx := log.WithCtxLevel(ctx, btclog.LevelTrace)
+//	...
+//	log.TraceS(ctx, "Processing request") // Logged unless the level is Off.
+func WithCtxLevel(ctx context.Context, level btclog.Level) context.Context {
+	return context.WithValue(ctx, levelKey{}, toSlogLevel(level))
+}
+
+// ctxLevelEnabled returns true if the context has a level associated with it
+// via WithCtxLevel and the given level is at or above it. It always returns
+// false if the handler's level is off.
+func ctxLevelEnabled(ctx context.Context, handlerLevel,
+	level slog.Level) bool {
+
+	if ctx == nil || handlerLevel >= levelOff {
+		return false
+	}
+
+	ctxLevel, ok := ctx.Value(levelKey{}).(slog.Level)
+
+	return ok && ctxLevel <= level
+}
+
+// ContextExtractor is a call-back that derives logging attributes from the
+// context that a record is logged with. It is called for every record, so it
+// should be cheap, and it must return nil if the context holds nothing of
+// interest.
+type ContextExtractor func(ctx context.Context) []slog.Attr
+
+// extractCtxAttrs returns a copy of the record with the attributes derived
+// from the context by the given extractors added in front of the record's own
+// attributes. The record is returned as is if no attributes were derived.
+func extractCtxAttrs(ctx context.Context, r slog.Record,
+	extractors []ContextExtractor) slog.Record {
+
+	if ctx == nil || len(extractors) == 0 {
+		return r
+	}
+
+	var attrs []slog.Attr
+	for _, extract := range extractors {
+		attrs = append(attrs, extract(ctx)...)
+	}
+	if len(attrs) == 0 {
+		return r
+	}
+
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	nr.AddAttrs(attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		nr.AddAttrs(a)
+		return true
+	})
 
-	return resp
+	return nr
 }
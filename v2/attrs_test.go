@@ -0,0 +1,161 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"testing"
+)
+
+// TestCtxAttrs tests that context attributes are deduplicated by key, that
+// they can be removed and that attributes given at the call-site take
+// precedence.
+func TestCtxAttrs(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	log := NewSLogger(NewDefaultHandler(&buf, WithNoTimestamp()))
+
+	ctx := WithCtx(context.Background(), "height", 1, "peer", "alice")
+	ctx = WithCtx(ctx, "height", 2, slog.Group("req", "id", 7))
+	log.InfoS(ctx, "Override")
+
+	ctx = WithoutCtx(ctx, "peer", "unknown")
+	log.InfoS(ctx, "Remove")
+
+	ctx = WithCtxAttrs(ctx, slog.Int("height", 3), slog.Bool("ok", true))
+	log.InfoS(ctx, "Call-site", "height", 4)
+
+	expected := `[INF]: Override height=2 peer=alice req.id=7
+[INF]: Remove height=2 req.id=7
+[INF]: Call-site req.id=7 ok=true height=4
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+// TestWithCtxMalformed tests that malformed context attributes are logged with
+// the !BADKEY key instead of causing a panic, that they don't replace each
+// other and that ValidateCtxAttrs reports them.
+func TestWithCtxMalformed(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	log := NewSLogger(NewDefaultHandler(&buf, WithNoTimestamp()))
+
+	ctx := WithCtx(context.Background(), 1, "height", 2)
+	ctx = WithCtx(ctx, "peer", "alice", "height")
+	log.InfoS(ctx, "Malformed")
+
+	expected := "[INF]: Malformed !BADKEY=1 height=2 peer=alice " +
+		"!BADKEY=height\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	// The same attributes are reported by ValidateCtxAttrs.
+	tests := map[string][]any{
+		"missing value":  {"peer", "alice", "height"},
+		"non-string key": {1, "height", 2},
+	}
+	for name, attrs := range tests {
+		err := ValidateCtxAttrs(attrs...)
+		if !errors.Is(err, ErrMalformedCtxAttrs) {
+			t.Fatalf("%s: expected ErrMalformedCtxAttrs, got %v",
+				name, err)
+		}
+	}
+
+	err := ValidateCtxAttrs("height", 1, slog.Int("peer", 2), "ok", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// TestBitcoinAttrs tests the formatting of the Bitcoin attribute constructors.
+func TestBitcoinAttrs(t *testing.T) {
+	t.Parallel()
+
+	var hash [32]byte
+	for i := range hash {
+		hash[i] = byte(i)
+	}
+	reversed := "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a0908" +
+		"0706050403020100"
+
+	pubKey := append([]byte{0x02}, hash[:]...)
+
+	tests := []struct {
+		attr     slog.Attr
+		expected string
+	}{
+		{Hash("txid", hash), reversed},
+		{Amount("amt", 0), "0.00000000"},
+		{Amount("amt", 100_000), "0.00100000"},
+		{Amount("amt", 2_100_000_000_000_000), "21000000.00000000"},
+		{Amount("amt", -150_000_000), "-1.50000000"},
+		{Amount("amt", math.MinInt64), "-92233720368.54775808"},
+		{OutPoint("op", hash, 3), reversed + ":3"},
+		{ShortChanID("scid", 539268<<40|845<<16|1), "539268x845x1"},
+		{PubKey("key", pubKey), "02000102...1c1d1e1f"},
+		{AbbrevHex("key", []byte{1, 2, 3, 4, 5, 6}, 6), "010203040506"},
+		{AbbrevHex("key", []byte{1, 2, 3, 4, 5, 6}, 2), "01...06"},
+	}
+	for _, test := range tests {
+		if got := test.attr.Value.String(); got != test.expected {
+			t.Fatalf("%s: expected %s, got %s", test.attr.Key,
+				test.expected, got)
+		}
+	}
+}
+
+// TestLazyAttrs tests that the values of lazy attributes are only computed for
+// records that are logged.
+func TestLazyAttrs(t *testing.T) {
+	t.Parallel()
+
+	var textBuf, jsonBuf bytes.Buffer
+	log := NewSLogger(NewMultiHandler(
+		NewDefaultHandler(&textBuf, WithNoTimestamp()),
+		NewJSONHandler(&jsonBuf, WithNoTimestamp()),
+	))
+	log.SetLevel(LevelInfo)
+
+	var calls int
+	dump := func() any {
+		calls++
+		return []int{1, 2}
+	}
+	ctx := WithCtx(context.Background(), Lazy("ctx", dump))
+
+	log.TraceS(ctx, "Dropped", Lazy("dump", dump),
+		LazySprintf("peer", "%s:%d", "127.0.0.1", 8333))
+	log.DebugS(ctx, "Dropped", Lazy("dump", dump))
+	if calls != 0 {
+		t.Fatalf("Expected no calls for disabled levels, got %d", calls)
+	}
+
+	log.InfoS(context.Background(), "Logged", Lazy("dump", dump),
+		LazySprintf("peer", "%s:%d", "127.0.0.1", 8333))
+
+	// Both handlers resolve the value.
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+
+	expectedText := "[INF]: Logged dump=\"[1 2]\" peer=127.0.0.1:8333\n"
+	if textBuf.String() != expectedText {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expectedText,
+			textBuf.String())
+	}
+
+	expectedJSON := `{"level":"INF","msg":"Logged","dump":[1,2],` +
+		`"peer":"127.0.0.1:8333"}` + "\n"
+	if jsonBuf.String() != expectedJSON {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expectedJSON,
+			jsonBuf.String())
+	}
+}
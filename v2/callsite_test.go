@@ -0,0 +1,83 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// TestWrappedCallSite tests that the call-site of a record is that of the
+// Logger's caller, however many Handlers wrap the DefaultHandler and the
+// JSONHandler.
+func TestWrappedCallSite(t *testing.T) {
+	t.Parallel()
+
+	wrappers := map[string]func(Handler) Handler{
+		"none": func(h Handler) Handler {
+			return h
+		},
+		"multi": func(h Handler) Handler {
+			return NewMultiHandler(h)
+		},
+		"ring": func(h Handler) Handler {
+			return NewRingHandler(h, 10, LevelInfo)
+		},
+		"dedup": func(h Handler) Handler {
+			return NewDedupHandler(h)
+		},
+		"sampling": func(h Handler) Handler {
+			return NewSamplingHandler(h)
+		},
+		"flight recorder": func(h Handler) Handler {
+			return NewFlightRecorderHandler(h)
+		},
+		"redact": func(h Handler) Handler {
+			return NewRedactHandler(h)
+		},
+		"nested": func(h Handler) Handler {
+			return NewMultiHandler(NewRedactHandler(
+				NewSamplingHandler(h),
+			))
+		},
+	}
+
+	for name, wrap := range wrappers {
+		t.Run(name, func(t *testing.T) {
+			var textBuf, jsonBuf bytes.Buffer
+			opts := []HandlerOption{
+				WithNoTimestamp(), WithCallerFlags(Lshortfile),
+			}
+			log := NewSLogger(wrap(NewMultiHandler(
+				NewDefaultHandler(&textBuf, opts...),
+				NewJSONHandler(&jsonBuf, opts...),
+			)).SubSystem("SUBS"))
+
+			_, _, line, _ := runtime.Caller(0)
+			log.Infof("Formatted")
+			log.InfoS(context.Background(), "Structured")
+
+			site1 := "callsite_test.go:" + strconv.Itoa(line+1)
+			site2 := "callsite_test.go:" + strconv.Itoa(line+2)
+
+			expectedText := "[INF] SUBS " + site1 +
+				": Formatted\n[INF] SUBS " + site2 +
+				": Structured\n"
+			if textBuf.String() != expectedText {
+				t.Fatalf("Expected:\n%s\nGot:\n%s",
+					expectedText, textBuf.String())
+			}
+
+			expectedJSON := `{"level":"INF","subsystem":"SUBS",` +
+				`"source":"` + site1 + `","msg":"Formatted"}` +
+				"\n" + `{"level":"INF","subsystem":"SUBS",` +
+				`"source":"` + site2 + `","msg":"Structured"}` +
+				"\n"
+			if jsonBuf.String() != expectedJSON {
+				t.Fatalf("Expected:\n%s\nGot:\n%s",
+					expectedJSON, jsonBuf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	btclogv1 "github.com/btcsuite/btclog"
+	"github.com/btcsuite/btclog/v2"
+	"github.com/btcsuite/btclog/v2/logreader"
+)
+
+// timeLayouts are the layouts accepted by the -since and -until flags, in
+// addition to a duration relative to the current time.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC3339Nano,
+}
+
+// attrFlags collects the values of all -attr flags.
+type attrFlags []logreader.Attr
+
+// String returns the attributes as a comma separated list.
+//
+// NOTE: This is part of the flag.Value interface.
+func (a *attrFlags) String() string {
+	parts := make([]string, 0, len(*a))
+	for _, attr := range *a {
+		parts = append(parts, attr.Key+"="+attr.Value)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Set adds an attribute of the form key=value. A key without a value matches
+// any record that has an attribute with that key.
+//
+// NOTE: This is part of the flag.Value interface.
+func (a *attrFlags) Set(s string) error {
+	key, value, _ := strings.Cut(s, "=")
+	if key == "" {
+		return errors.New("attribute key must not be empty")
+	}
+
+	*a = append(*a, logreader.Attr{Key: key, Value: value})
+
+	return nil
+}
+
+// filter decides which records are written.
+type filter struct {
+	level   btclogv1.Level
+	tags    map[string]bool
+	since   time.Time
+	until   time.Time
+	attrs   []logreader.Attr
+	message *regexp.Regexp
+}
+
+// match returns true if the record passes all the filter's conditions.
+func (f *filter) match(rec *logreader.Record) bool {
+	if rec.Level < f.level {
+		return false
+	}
+
+	if len(f.tags) > 0 && !f.tags[strings.ToUpper(rec.Tag)] {
+		return false
+	}
+
+	// Records without a timestamp can't be placed in a time range, so
+	// they are only filtered out if a range was given.
+	if !f.since.IsZero() && rec.Time.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() &&
+		(rec.Time.IsZero() || rec.Time.After(f.until)) {
+
+		return false
+	}
+
+	for _, attr := range f.attrs {
+		value, ok := rec.Attr(attr.Key)
+		if !ok || (attr.Value != "" && value != attr.Value) {
+			return false
+		}
+	}
+
+	if f.message != nil && !f.message.MatchString(rec.Message) {
+		return false
+	}
+
+	return true
+}
+
+// parseTime parses the value of a -since or -until flag, which is either a
+// duration that is subtracted from now or a timestamp in one of the
+// timeLayouts.
+func parseTime(s string, now time.Time, loc *time.Location) (time.Time,
+	error) {
+
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// filterConfig holds the parsed filter and output flags that are shared by
+// the filter and merge commands.
+type filterConfig struct {
+	filter filter
+	loc    *time.Location
+	output string
+	color  string
+	tail   int
+}
+
+// parseFilterFlags registers the shared filter and output flags on the flag
+// set and parses the given arguments. Any command specific flags must be
+// registered before.
+func parseFilterFlags(fs *flag.FlagSet, args []string,
+	now time.Time) (*filterConfig, error) {
+
+	var (
+		cfg                           filterConfig
+		attrs                         attrFlags
+		level, tags, since, until, tz string
+		message                       string
+	)
+	fs.StringVar(&level, "level", "trace", "only show records at or "+
+		"above this level")
+	fs.StringVar(&tags, "tag", "", "only show records of these "+
+		"comma separated sub-systems")
+	fs.StringVar(&since, "since", "", "only show records at or after "+
+		"this time or duration ago (e.g. 2024-10-03 13:00 or 1h)")
+	fs.StringVar(&until, "until", "", "only show records at or before "+
+		"this time or duration ago")
+	fs.Var(&attrs, "attr", "only show records with this key=value "+
+		"attribute, or with this key if no value is given; may be "+
+		"repeated")
+	fs.StringVar(&message, "grep", "", "only show records whose message "+
+		"matches this regular expression")
+	fs.StringVar(&tz, "tz", "Local", "time zone of the timestamps in "+
+		"the log files")
+	fs.StringVar(&cfg.output, "output", "text", "output format: text, "+
+		"json or csv")
+	fs.StringVar(&cfg.color, "color", "auto", "color the text output: "+
+		"auto, always or never")
+	fs.IntVar(&cfg.tail, "tail", 0, "only show the last n matching "+
+		"records of the existing input")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	cfg.filter.level, ok = btclog.LevelFromString(level)
+	if !ok {
+		return nil, fmt.Errorf("invalid level %q", level)
+	}
+
+	if tags != "" {
+		cfg.filter.tags = make(map[string]bool)
+		for _, tag := range strings.Split(tags, ",") {
+			tag = strings.ToUpper(strings.TrimSpace(tag))
+			cfg.filter.tags[tag] = true
+		}
+	}
+
+	var err error
+	cfg.loc, err = time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone: %w", err)
+	}
+
+	cfg.filter.since, err = parseTime(since, now, cfg.loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -since: %w", err)
+	}
+	cfg.filter.until, err = parseTime(until, now, cfg.loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -until: %w", err)
+	}
+
+	cfg.filter.attrs = attrs
+
+	if message != "" {
+		cfg.filter.message, err = regexp.Compile(message)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -grep: %w", err)
+		}
+	}
+
+	if cfg.tail < 0 {
+		return nil, errors.New("-tail must not be negative")
+	}
+
+	return &cfg, nil
+}
+
+// printer writes the records that match a filter to a recordWriter.
+type printer struct {
+	filter *filter
+	out    recordWriter
+
+	// tail holds back the last matching records until the end of the
+	// existing input has been reached. It is nil if -tail isn't used.
+	tail *tailBuffer
+}
+
+// newPrinter creates a printer for the given config that writes to w. The
+// labels are those of all sources that records are printed for, if any.
+func newPrinter(cfg *filterConfig, w io.Writer,
+	labels []string) (*printer, error) {
+
+	out, err := newRecordWriter(w, cfg.output, cfg.color, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	return &printer{
+		filter: &cfg.filter,
+		out:    out,
+		tail:   newTailBuffer(cfg.tail),
+	}, nil
+}
+
+// print writes the record if it matches the filter.
+func (p *printer) print(rec *logreader.Record, label string) error {
+	if !p.filter.match(rec) {
+		return nil
+	}
+
+	if p.tail != nil {
+		p.tail.add(tailEntry{rec: rec, label: label})
+		return nil
+	}
+
+	return p.out.Write(rec, label)
+}
+
+// caughtUp must be called whenever the end of the input is reached. It writes
+// any held back records and flushes the output.
+func (p *printer) caughtUp() error {
+	if p.tail != nil {
+		entries := p.tail.entries()
+		p.tail = nil
+		for _, e := range entries {
+			if err := p.out.Write(e.rec, e.label); err != nil {
+				return err
+			}
+		}
+	}
+
+	return p.out.Flush()
+}
+
+// runFilter reads the records of all given files, or stdin if there are none,
+// and writes those that match the filter to stdout.
+func runFilter(ctx context.Context, args []string, stdin io.Reader, stdout,
+	stderr io.Writer) error {
+
+	fs := newFlagSet("filter", "[flags] [file ...]", stderr)
+
+	var followFile bool
+	fs.BoolVar(&followFile, "follow", false, "wait for new records to "+
+		"be appended to the file")
+
+	cfg, err := parseFilterFlags(fs, args, time.Now())
+	if err != nil {
+		return err
+	}
+	files := fs.Args()
+
+	p, err := newPrinter(cfg, stdout, nil)
+	if err != nil {
+		return err
+	}
+	emit := func(rec *logreader.Record) error {
+		return p.print(rec, "")
+	}
+
+	switch {
+	case followFile:
+		if len(files) != 1 {
+			return errors.New("-follow requires exactly one file")
+		}
+
+		err := follow(ctx, files[0], cfg.loc, emit, p.caughtUp)
+		if err != nil {
+			return err
+		}
+
+	case len(files) == 0:
+		if err := scan(stdin, cfg.loc, emit); err != nil {
+			return err
+		}
+
+	default:
+		for _, name := range files {
+			if err := scanFile(name, cfg.loc, emit); err != nil {
+				return err
+			}
+		}
+	}
+
+	return p.caughtUp()
+}
+
+// scanFile calls emit for each record in the named file.
+func scanFile(name string, loc *time.Location,
+	emit func(*logreader.Record) error) error {
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := scan(f, loc, emit); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
+
+// scan calls emit for each record read from r.
+func scan(r io.Reader, loc *time.Location,
+	emit func(*logreader.Record) error) error {
+
+	s := logreader.NewScanner(r, logreader.WithLocation(loc))
+	for s.Next() {
+		if err := emit(s.Record()); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+// tailEntry is a record held back by a tailBuffer along with the label of its
+// source.
+type tailEntry struct {
+	rec   *logreader.Record
+	label string
+}
+
+// tailBuffer keeps the last n entries that were added to it.
+type tailBuffer struct {
+	buf  []tailEntry
+	next int
+	full bool
+}
+
+// newTailBuffer returns a tailBuffer for n entries, or nil if n is zero.
+func newTailBuffer(n int) *tailBuffer {
+	if n == 0 {
+		return nil
+	}
+
+	return &tailBuffer{
+		buf: make([]tailEntry, n),
+	}
+}
+
+// add stores e, overwriting the oldest entry if the buffer is full.
+func (t *tailBuffer) add(e tailEntry) {
+	t.buf[t.next] = e
+	t.next++
+	if t.next == len(t.buf) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// entries returns the stored entries, oldest first.
+func (t *tailBuffer) entries() []tailEntry {
+	if !t.full {
+		return t.buf[:t.next]
+	}
+
+	entries := make([]tailEntry, 0, len(t.buf))
+	entries = append(entries, t.buf[t.next:]...)
+
+	return append(entries, t.buf[:t.next]...)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btclog/v2/logreader"
+)
+
+// followPollInterval is the interval at which a followed file is checked for
+// new data once its end has been reached.
+const followPollInterval = 250 * time.Millisecond
+
+// follow calls emit for each record in the named file and then keeps waiting
+// for new records to be appended to it until the context is cancelled.
+// caughtUp is called each time the end of the file is reached. If the file is
+// rotated, the new file is followed from its start.
+//
+// Since the DefaultHandler writes each record, including multi-line messages,
+// with a single write, a record is considered complete once the end of the
+// file is reached rather than only once the next record starts. This way new
+// records are shown without delay.
+func follow(ctx context.Context, name string, loc *time.Location,
+	emit func(*logreader.Record) error, caughtUp func() error) error {
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	var (
+		r       = bufio.NewReader(f)
+		next    *os.File
+		partial string
+		pending strings.Builder
+	)
+	defer func() {
+		f.Close()
+		if next != nil {
+			next.Close()
+		}
+	}()
+
+	flush := func() error {
+		if pending.Len() == 0 {
+			return nil
+		}
+
+		text := pending.String()
+		pending.Reset()
+
+		rec, err := logreader.ParseLine(text, loc)
+		if err != nil {
+			return err
+		}
+
+		return emit(rec)
+	}
+
+	for {
+		line, err := r.ReadString('\n')
+		switch {
+		// An incomplete line at the end of the file is kept until the
+		// rest of it has been written.
+		case errors.Is(err, io.EOF):
+			partial += line
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := caughtUp(); err != nil {
+				return err
+			}
+
+			// Once the rotated file has been drained, the new
+			// one is read from its start.
+			if next != nil {
+				f.Close()
+				f, next, partial = next, nil, ""
+				r.Reset(f)
+				continue
+			}
+
+			select {
+			case <-time.After(followPollInterval):
+			case <-ctx.Done():
+				return nil
+			}
+
+			// Anything that was written to the rotated file
+			// before the new one was created is still read before
+			// switching to the new file.
+			if rotated(f, name) {
+				next, err = os.Open(name)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+
+		case err != nil:
+			return err
+		}
+
+		line = strings.TrimRight(partial+line, "\r\n")
+		partial = ""
+
+		// JSON lines are complete records on their own.
+		if strings.HasPrefix(line, "{") {
+			rec, err := logreader.ParseJSONLine(line, loc)
+			if err == nil {
+				if err := flush(); err != nil {
+					return err
+				}
+				if err := emit(rec); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		// Continuation lines are joined with the pending record while
+		// any orphaned lines before the first header are skipped.
+		_, err = logreader.ParseLine(line, loc)
+		if errors.Is(err, logreader.ErrNotHeader) {
+			if pending.Len() > 0 {
+				pending.WriteByte('\n')
+				pending.WriteString(line)
+			}
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+		pending.WriteString(line)
+	}
+}
+
+// rotated returns true if name no longer refers to the open file f.
+func rotated(f *os.File, name string) bool {
+	cur, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return false
+	}
+
+	return !os.SameFile(cur, info)
+}
@@ -0,0 +1,95 @@
+// Command btclog reads log files written by the btclog DefaultHandler or
+// JSONHandler (or the v1 Backend), filters the records and writes them back
+// out as colored text, JSON or CSV.
+//
+// Usage:
+//
+//	btclog [filter] [flags] [file ...]
+//	btclog merge [flags] [label=]file ...
+//
+// If no files are given, the records are read from stdin. Since the log lines
+// are parsed rather than matched as plain text, quoted attributes and
+// multi-line messages are handled correctly. For example, the following
+// prints all warnings and errors of the PEER and SRVR sub-systems that were
+// logged within the last hour and that relate to a specific peer:
+//
+//	btclog -level warn -tag PEER,SRVR -since 1h -attr peer=1.2.3.4:8333 lnd.log
+//
+// The merge command interleaves the records of several logs, for example those
+// of the nodes of a test network, into a single timeline. Each line is
+// prefixed with the label of the log it was read from:
+//
+//	btclog merge -since 10m alice=alice/lnd.log bob=bob/lnd.log
+//
+// Run "btclog -h" or "btclog merge -h" for a list of all flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+// command runs a sub-command of the btclog tool with the given arguments,
+// excluding the command name itself.
+type command func(ctx context.Context, args []string, stdin io.Reader,
+	stdout, stderr io.Writer) error
+
+// commands holds all sub-commands by name.
+var commands = map[string]command{
+	"filter": runFilter,
+	"merge":  runMerge,
+}
+
+// defaultCommand is the command that is run if the first argument is not the
+// name of a command.
+const defaultCommand = "filter"
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	switch {
+	case err == nil:
+
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+
+	default:
+		fmt.Fprintf(os.Stderr, "btclog: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches the given arguments to the matching sub-command.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout,
+	stderr io.Writer) error {
+
+	name := defaultCommand
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			name, args = args[0], args[1:]
+		}
+	}
+
+	return commands[name](ctx, args, stdin, stdout, stderr)
+}
+
+// newFlagSet creates a new flag set for the named command that writes its
+// usage to stderr.
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("btclog "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: btclog %s %s\n\nFlags:\n", name,
+			usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
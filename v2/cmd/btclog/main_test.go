@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog/v2/logreader"
+)
+
+// testLog is a log file in the DefaultHandler format that is used by the
+// tests.
+const testLog = `2024-10-03 13:00:00.000 [INF] PEER: Connected peer=1.2.3.4:8333
+2024-10-03 13:00:01.000 [DBG] PEER peer.go:12: Received msg cmd=ping
+2024-10-03 13:00:02.000 [ERR] SRVR: Unable to start
+  caused by something err="no such file"
+2024-10-03 13:00:03.000 [WRN] PEER: Disconnected peer=[::1]:8333 ` +
+	`reason="ban score"
+2024-10-03 13:00:04.000 [INF] RPCS: Started server
+`
+
+// TestFilter tests that the filter command writes the expected records in
+// each of the output formats.
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(file, []byte(testLog), 0600); err != nil {
+		t.Fatalf("Unable to write log: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "level and tag",
+			args: []string{"-level", "info", "-tag", "peer,srvr"},
+			expected: `2024-10-03 13:00:00.000 [INF] PEER: Connected ` +
+				`peer=1.2.3.4:8333
+2024-10-03 13:00:02.000 [ERR] SRVR: Unable to start
+  caused by something err="no such file"
+2024-10-03 13:00:03.000 [WRN] PEER: Disconnected peer=[::1]:8333 ` +
+				`reason="ban score"
+`,
+		},
+		{
+			name: "attribute and time range",
+			args: []string{
+				"-attr", "reason=ban score", "-attr", "peer",
+				"-since", "2024-10-03 13:00:01",
+				"-until", "2024-10-03 13:00:03",
+			},
+			expected: `2024-10-03 13:00:03.000 [WRN] PEER: Disconnected ` +
+				`peer=[::1]:8333 reason="ban score"
+`,
+		},
+		{
+			name: "message regex as JSON",
+			args: []string{"-grep", "^Re", "-output", "json"},
+			expected: `{"time":"2024-10-03T13:00:01.000Z",` +
+				`"level":"DBG","subsystem":"PEER",` +
+				`"source":"peer.go:12","msg":"Received msg",` +
+				`"cmd":"ping"}
+`,
+		},
+		{
+			name: "tail as CSV",
+			args: []string{
+				"-tail", "2", "-level", "error",
+				"-output", "csv",
+			},
+			expected: `time,level,subsystem,source,msg,attrs
+2024-10-03T13:00:02.000Z,ERR,SRVR,,"Unable to start
+  caused by something","err=""no such file"""
+`,
+		},
+		{
+			name: "tail",
+			args: []string{"-tail", "2", "-tag", "PEER"},
+			expected: `2024-10-03 13:00:01.000 [DBG] PEER ` +
+				`peer.go:12: Received msg cmd=ping
+2024-10-03 13:00:03.000 [WRN] PEER: Disconnected peer=[::1]:8333 ` +
+				`reason="ban score"
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := append([]string{
+				"-tz", "UTC", "-color", "never",
+			}, test.args...)
+			args = append(args, file)
+
+			var stdout, stderr bytes.Buffer
+			ctx := context.Background()
+			err := run(ctx, args, nil, &stdout, &stderr)
+			if err != nil {
+				t.Fatalf("Unable to run: %v (%s)", err,
+					stderr.String())
+			}
+
+			if stdout.String() != test.expected {
+				t.Fatalf("Expected:\n%s\nGot:\n%s",
+					test.expected, stdout.String())
+			}
+		})
+	}
+}
+
+// TestFilterStdin tests that records are read from stdin if no files are
+// given and that the filter sub-command can be named explicitly.
+func TestFilterStdin(t *testing.T) {
+	t.Parallel()
+
+	var stdout bytes.Buffer
+	err := run(
+		context.Background(),
+		[]string{"filter", "-tz", "UTC", "-tag", "RPCS"},
+		strings.NewReader(testLog), &stdout, &bytes.Buffer{},
+	)
+	if err != nil {
+		t.Fatalf("Unable to run: %v", err)
+	}
+
+	expected := "2024-10-03 13:00:04.000 [INF] RPCS: Started server\n"
+	if stdout.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, stdout.String())
+	}
+}
+
+// TestMerge tests that the merge command interleaves the records of several
+// files and labels each of them.
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	alice := filepath.Join(dir, "alice.log")
+	bob := filepath.Join(dir, "bob.log")
+
+	err := os.WriteFile(alice, []byte(testLog), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write log: %v", err)
+	}
+	err = os.WriteFile(bob, []byte(
+		`{"time":"2024-10-03T13:00:02.000Z","level":"INF",`+
+			`"subsystem":"HSWC","msg":"Forwarded","amt":1000}`+"\n",
+	), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write log: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	err = run(context.Background(), []string{
+		"merge", "-tz", "UTC", "-level", "info", "-since",
+		"2024-10-03 13:00:01", "alice=" + alice, "bob=" + bob,
+	}, nil, &stdout, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Unable to run: %v", err)
+	}
+
+	expected := `alice 2024-10-03 13:00:02.000 [ERR] SRVR: Unable to start
+  caused by something err="no such file"
+bob   2024-10-03 13:00:02.000 [INF] HSWC: Forwarded amt=1000
+alice 2024-10-03 13:00:03.000 [WRN] PEER: Disconnected ` +
+		`peer=[::1]:8333 reason="ban score"
+alice 2024-10-03 13:00:04.000 [INF] RPCS: Started server
+`
+	if stdout.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, stdout.String())
+	}
+}
+
+// TestColorAuto tests that the auto color mode follows the same environment
+// variables as the btclog DefaultHandler.
+func TestColorAuto(t *testing.T) {
+	for force, expected := range map[string]bool{"": false, "1": true} {
+		t.Setenv("FORCE_COLOR", force)
+
+		rw, err := newRecordWriter(&bytes.Buffer{}, "text", "auto", nil)
+		if err != nil {
+			t.Fatalf("Unable to create writer: %v", err)
+		}
+
+		colored := rw.(*textWriter).theme != nil
+		if colored != expected {
+			t.Fatalf("FORCE_COLOR=%q: expected colors %v, got %v",
+				force, expected, colored)
+		}
+	}
+}
+
+// TestFollow tests that a followed file is read while it is being written,
+// including across a rotation, without losing or repeating any records.
+func TestFollow(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.log")
+
+	// The file starts with a multi-line record followed by an incomplete
+	// line.
+	err := os.WriteFile(file, []byte(
+		"2024-10-03 13:00:00.000 [INF] PEER: one\n  continued\n"+
+			"2024-10-03 13:00:01.000 [INF] PEER: tw",
+	), 0600)
+	if err != nil {
+		t.Fatalf("Unable to write log: %v", err)
+	}
+
+	appendFile := func(name, data string) {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			t.Errorf("Unable to open log: %v", err)
+			return
+		}
+		defer f.Close()
+
+		if _, err := f.WriteString(data); err != nil {
+			t.Errorf("Unable to write log: %v", err)
+		}
+	}
+
+	// The test is bounded in case the file is never caught up with.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var msgs []string
+	emit := func(rec *logreader.Record) error {
+		msgs = append(msgs, rec.Message)
+		return nil
+	}
+
+	// Each time the end of the file is reached, the next step is taken.
+	var step int
+	caughtUp := func() error {
+		step++
+		switch step {
+		// Complete the incomplete line and add a JSON record.
+		case 1:
+			appendFile(file, "o\n"+`{"time":"2024-10-03T13:00:02Z",`+
+				`"level":"INF","msg":"json"}`+"\n")
+
+		// Rotate the file. A record is still appended to the rotated
+		// file before the new one is followed.
+		case 2:
+			rotated := file + ".1"
+			if err := os.Rename(file, rotated); err != nil {
+				t.Errorf("Unable to rotate log: %v", err)
+			}
+			appendFile(rotated,
+				"2024-10-03 13:00:03.000 [INF] PEER: three\n")
+
+			err := os.WriteFile(file, []byte(
+				"2024-10-03 13:00:04.000 [INF] PEER: four\n",
+			), 0600)
+			if err != nil {
+				t.Errorf("Unable to write log: %v", err)
+			}
+
+		// The rotated file has been drained.
+		case 3:
+
+		// The new file has been read.
+		case 4:
+			cancel()
+		}
+
+		return nil
+	}
+
+	if err := follow(ctx, file, time.UTC, emit, caughtUp); err != nil {
+		t.Fatalf("Unable to follow: %v", err)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("Timed out after step %d, got %q", step, msgs)
+	}
+
+	expected := []string{"one\n  continued", "two", "json", "three", "four"}
+	if strings.Join(msgs, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Expected %q, got %q", expected, msgs)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btclog/v2/logreader"
+)
+
+// mergeUsage is the usage of the merge command's arguments.
+const mergeUsage = "[flags] [label=]file ..."
+
+// parseSource parses a merge argument of the form [label=]file. If no label is
+// given, the file name is used as the label.
+func parseSource(arg string) (string, string) {
+	label, name, ok := strings.Cut(arg, "=")
+	if !ok || label == "" || strings.ContainsAny(label, `/\`) {
+		return arg, arg
+	}
+
+	return label, name
+}
+
+// runMerge reads the records of all given files, interleaves them by their
+// timestamps and writes those that match the filter to stdout, each prefixed
+// with the label of its file.
+func runMerge(_ context.Context, args []string, _ io.Reader, stdout,
+	stderr io.Writer) error {
+
+	fs := newFlagSet("merge", mergeUsage, stderr)
+
+	cfg, err := parseFilterFlags(fs, args, time.Now())
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no files to merge")
+	}
+
+	var (
+		sources = make([]logreader.Source, 0, fs.NArg())
+		labels  = make([]string, 0, fs.NArg())
+	)
+	for _, arg := range fs.Args() {
+		label, name := parseSource(arg)
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		sources = append(sources, logreader.Source{
+			Label:  label,
+			Reader: f,
+		})
+		labels = append(labels, label)
+	}
+
+	p, err := newPrinter(cfg, stdout, labels)
+	if err != nil {
+		return err
+	}
+
+	m := logreader.NewMerger(sources, logreader.WithLocation(cfg.loc))
+	for m.Next() {
+		rec := m.Record()
+		if err := p.print(rec.Record, rec.Label); err != nil {
+			return err
+		}
+	}
+	if err := m.Err(); err != nil {
+		return fmt.Errorf("unable to merge: %w", err)
+	}
+
+	return p.caughtUp()
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/btcsuite/btclog/v2"
+	"github.com/btcsuite/btclog/v2/logreader"
+)
+
+// textTimeFormat is the layout of the timestamps of the text output. It matches
+// the layout used by the btclog DefaultHandler.
+const textTimeFormat = "2006-01-02 15:04:05.000"
+
+// jsonTimeFormat is the layout of the "time" field of the JSON output. It
+// matches the layout used by the btclog JSONHandler.
+const jsonTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// recordWriter writes records in a particular output format.
+type recordWriter interface {
+	// Write writes a single record. The label identifies the source of the
+	// record and is only written if the recordWriter was created with a
+	// set of labels.
+	Write(rec *logreader.Record, label string) error
+
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+}
+
+// newRecordWriter returns a recordWriter for the named output format. The
+// color mode is only used by the text format. If labels is not nil, each
+// record is written along with the label of its source.
+func newRecordWriter(w io.Writer, format, color string,
+	labels []string) (recordWriter, error) {
+
+	withLabels := labels != nil
+
+	switch format {
+	case "text":
+		var theme *btclog.ColorTheme
+		switch color {
+		case "always":
+			theme = &btclog.DefaultColorTheme
+
+		case "auto":
+			if btclog.ColorEnabled(w) {
+				theme = &btclog.DefaultColorTheme
+			}
+
+		case "never":
+
+		default:
+			return nil, fmt.Errorf("invalid color mode %q", color)
+		}
+
+		// The labels are padded to the same width so that the log
+		// lines stay aligned.
+		labelWidth := -1
+		for _, label := range labels {
+			labelWidth = max(labelWidth, len(label))
+		}
+
+		return &textWriter{
+			w:          bufio.NewWriter(w),
+			theme:      theme,
+			labelWidth: labelWidth,
+		}, nil
+
+	case "json":
+		return &jsonWriter{
+			w:          bufio.NewWriter(w),
+			withLabels: withLabels,
+		}, nil
+
+	case "csv":
+		header := []string{
+			"time", "level", "subsystem", "source", "msg", "attrs",
+		}
+		if withLabels {
+			header = append([]string{"label"}, header...)
+		}
+
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return nil, err
+		}
+
+		return &csvWriter{
+			w:          cw,
+			withLabels: withLabels,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid output format %q", format)
+	}
+}
+
+// textWriter writes records in the DefaultHandler format, optionally colored
+// with a theme. Labels are written in front of each line.
+type textWriter struct {
+	w     *bufio.Writer
+	theme *btclog.ColorTheme
+
+	// labelWidth is the width that labels are padded to, or -1 if labels
+	// aren't written.
+	labelWidth int
+}
+
+// Write writes a single record.
+//
+// NOTE: This is part of the recordWriter interface.
+func (t *textWriter) Write(rec *logreader.Record, label string) error {
+	var sb strings.Builder
+	if t.labelWidth >= 0 {
+		fmt.Fprintf(&sb, "%-*s ", t.labelWidth, label)
+	}
+
+	if t.theme == nil {
+		sb.WriteString(rec.String() + "\n")
+		_, err := t.w.WriteString(sb.String())
+
+		return err
+	}
+
+	if !rec.Time.IsZero() {
+		ts := rec.Time.Format(textTimeFormat)
+		sb.WriteString(btclog.Colorize(t.theme.Timestamp, ts))
+		sb.WriteByte(' ')
+	}
+
+	sb.WriteString(btclog.Colorize(
+		t.theme.Levels[rec.Level], "["+rec.Level.String()+"]",
+	))
+	if rec.Tag != "" {
+		sb.WriteString(" " + rec.Tag)
+	}
+	if rec.File != "" {
+		callSite := btclog.Colorize(t.theme.CallSite, rec.CallSite())
+		sb.WriteString(" " + callSite)
+	}
+	sb.WriteString(": " + rec.Message)
+
+	for _, a := range rec.Attrs {
+		value := logreader.Quote(a.Value)
+		if a.Key == "err" {
+			value = btclog.Colorize(t.theme.ErrValue, value)
+		}
+
+		key := btclog.Colorize(t.theme.Key, logreader.Quote(a.Key))
+		sb.WriteString(" " + key + "=" + value)
+	}
+	sb.WriteByte('\n')
+
+	_, err := t.w.WriteString(sb.String())
+
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+//
+// NOTE: This is part of the recordWriter interface.
+func (t *textWriter) Flush() error {
+	return t.w.Flush()
+}
+
+// jsonWriter writes each record as a JSON object on a single line, using the
+// same keys as the btclog JSONHandler. All attribute values are strings since
+// their original types are not known. Labels are written as the first field
+// with the "label" key.
+type jsonWriter struct {
+	w          *bufio.Writer
+	withLabels bool
+}
+
+// Write writes a single record.
+//
+// NOTE: This is part of the recordWriter interface.
+func (j *jsonWriter) Write(rec *logreader.Record, label string) error {
+	// The fields are written one by one rather than via a map in order to
+	// preserve their order.
+	buf := []byte{'{'}
+	field := func(key, value string) {
+		if len(buf) > 1 {
+			buf = append(buf, ',')
+		}
+		// Marshalling a string never fails.
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(value)
+
+		buf = append(buf, k...)
+		buf = append(buf, ':')
+		buf = append(buf, v...)
+	}
+
+	if j.withLabels {
+		field("label", label)
+	}
+	if !rec.Time.IsZero() {
+		field("time", rec.Time.Format(jsonTimeFormat))
+	}
+	field("level", rec.Level.String())
+	if rec.Tag != "" {
+		field("subsystem", rec.Tag)
+	}
+	if rec.File != "" {
+		field("source", rec.CallSite())
+	}
+	field("msg", rec.Message)
+	for _, a := range rec.Attrs {
+		field(a.Key, a.Value)
+	}
+	buf = append(buf, '}', '\n')
+
+	_, err := j.w.Write(buf)
+
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+//
+// NOTE: This is part of the recordWriter interface.
+func (j *jsonWriter) Flush() error {
+	return j.w.Flush()
+}
+
+// csvWriter writes each record as a CSV row. The attributes are combined into
+// a single column in the key=value form of the DefaultHandler. Labels are
+// written to the first column.
+type csvWriter struct {
+	w          *csv.Writer
+	withLabels bool
+}
+
+// Write writes a single record.
+//
+// NOTE: This is part of the recordWriter interface.
+func (c *csvWriter) Write(rec *logreader.Record, label string) error {
+	var ts string
+	if !rec.Time.IsZero() {
+		ts = rec.Time.Format(jsonTimeFormat)
+	}
+
+	attrs := make([]string, 0, len(rec.Attrs))
+	for _, a := range rec.Attrs {
+		attrs = append(attrs, logreader.Quote(a.Key)+"="+
+			logreader.Quote(a.Value))
+	}
+
+	row := []string{
+		ts, rec.Level.String(), rec.Tag, rec.CallSite(), rec.Message,
+		strings.Join(attrs, " "),
+	}
+	if c.withLabels {
+		row = append([]string{label}, row...)
+	}
+
+	return c.w.Write(row)
+}
+
+// Flush writes any buffered data to the underlying writer.
+//
+// NOTE: This is part of the recordWriter interface.
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+
+	return c.w.Error()
+}
@@ -0,0 +1,152 @@
+package btclog
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/btcsuite/btclog"
+)
+
+// ANSI escape sequences used by the DefaultColorTheme.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+	ansiBoldRed = "\x1b[1;31m"
+)
+
+// ColorTheme defines the ANSI escape sequences that are used to color the
+// different parts of a log line. An empty sequence leaves that part of the log
+// line unstyled.
+type ColorTheme struct {
+	// Levels maps each level to the sequence used for the level tag.
+	Levels map[btclog.Level]string
+
+	// Timestamp is the sequence used for the timestamp.
+	Timestamp string
+
+	// CallSite is the sequence used for the call-site.
+	CallSite string
+
+	// Key is the sequence used for the key of each attribute.
+	Key string
+
+	// ErrValue is the sequence used for the value of an "err" attribute.
+	ErrValue string
+}
+
+// DefaultColorTheme is a ColorTheme that works well on both light and dark
+// terminal backgrounds.
+var DefaultColorTheme = ColorTheme{
+	Levels: map[btclog.Level]string{
+		LevelTrace:    ansiMagenta,
+		LevelDebug:    ansiBlue,
+		LevelInfo:     ansiGreen,
+		LevelWarn:     ansiYellow,
+		LevelError:    ansiRed,
+		LevelCritical: ansiBoldRed,
+	},
+	Timestamp: ansiDim,
+	CallSite:  ansiDim,
+	Key:       ansiCyan,
+	ErrValue:  ansiRed,
+}
+
+// WithColorTheme can be used to color the output of the DefaultHandler with
+// the given theme. Colors are only used if the writer is a terminal, unless
+// overridden by the environment: a non-empty FORCE_COLOR other than "0" always
+// enables colors while FORCE_COLOR=0 or a non-empty NO_COLOR disables them.
+//
+// NOTE: the theme replaces any call-backs set with the WithStyled* options.
+func WithColorTheme(theme ColorTheme) HandlerOption {
+	return func(opts *handlerOpts) {
+		opts.colorTheme = &theme
+	}
+}
+
+// WithColor can be used to color the output of the DefaultHandler with the
+// DefaultColorTheme. See WithColorTheme for when colors are used.
+func WithColor() HandlerOption {
+	return WithColorTheme(DefaultColorTheme)
+}
+
+// apply sets the styling call-backs of the given options to those of the
+// theme.
+func (t *ColorTheme) apply(opts *handlerOpts) {
+	opts.styledLevel = func(level btclog.Level) string {
+		return Colorize(t.Levels[level], "["+level.String()+"]")
+	}
+	opts.styledTimestamp = func(ts string) string {
+		return Colorize(t.Timestamp, ts)
+	}
+	opts.styledCallSite = func(file string, line int) string {
+		return Colorize(t.CallSite, file+":"+strconv.Itoa(line))
+	}
+
+	// The key string passed to the call-back includes the '=', which is
+	// left unstyled.
+	opts.styledKey = func(key string) string {
+		if len(key) == 0 || t.Key == "" {
+			return key
+		}
+		return Colorize(t.Key, key[:len(key)-1]) + "="
+	}
+	opts.styledValue = func(key, value string) string {
+		if key != "err" {
+			return value
+		}
+		return Colorize(t.ErrValue, value)
+	}
+}
+
+// Colorize wraps s in the given escape sequence, such as one of a ColorTheme,
+// followed by a reset. If the sequence is empty, s is returned as is.
+func Colorize(seq, s string) string {
+	if seq == "" {
+		return s
+	}
+
+	return seq + s + ansiReset
+}
+
+// ColorEnabled determines whether colors should be used when writing to w. It
+// is used by the DefaultHandler when a ColorTheme is set and can be used by
+// other tools to make the same decision: a non-empty FORCE_COLOR other than "0"
+// always enables colors while FORCE_COLOR=0, a non-empty NO_COLOR or
+// TERM=dumb disable them. Otherwise colors are used if w is a terminal.
+func ColorEnabled(w io.Writer) bool {
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok && force != "" {
+		return force != "0"
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	return isTerminal(w)
+}
+
+// isTerminal returns true if w is a file that refers to a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,108 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestColorEnabled tests that the environment variables take precedence over
+// the terminal detection.
+func TestColorEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		force    string
+		noColor  string
+		expected bool
+	}{
+		{
+			name:     "not a terminal",
+			expected: false,
+		},
+		{
+			name:     "forced",
+			force:    "1",
+			expected: true,
+		},
+		{
+			name:     "forced off",
+			force:    "0",
+			expected: false,
+		},
+		{
+			name:     "forced despite NO_COLOR",
+			force:    "true",
+			noColor:  "1",
+			expected: true,
+		},
+		{
+			name:     "NO_COLOR",
+			noColor:  "1",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("FORCE_COLOR", test.force)
+			t.Setenv("NO_COLOR", test.noColor)
+
+			if ColorEnabled(&bytes.Buffer{}) != test.expected {
+				t.Fatalf("Expected %v", test.expected)
+			}
+		})
+	}
+
+	// A regular file is never a terminal.
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	if err != nil {
+		t.Fatalf("Unable to create file: %v", err)
+	}
+	defer f.Close()
+
+	if isTerminal(f) {
+		t.Fatalf("Regular file detected as terminal")
+	}
+}
+
+// TestColorTheme tests that the color theme styles each part of the log line.
+func TestColorTheme(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "1")
+
+	timeSource := func() time.Time {
+		return time.Date(2024, 10, 3, 13, 34, 17, 123e6, time.UTC)
+	}
+
+	var buf bytes.Buffer
+	log := NewSLogger(NewDefaultHandler(
+		&buf, WithColor(), WithTimeSource(timeSource),
+		WithCallerFlags(Lshortfile),
+	).SubSystem("SUBS"))
+
+	log.ErrorS(context.Background(), "Failed", errors.New("oh no"),
+		"key", 5)
+
+	expected := "\x1b[2m2024-10-03 13:34:17.123\x1b[0m " +
+		"\x1b[31m[ERR]\x1b[0m SUBS " +
+		"\x1b[2mcolor_test.go:85\x1b[0m: Failed " +
+		"\x1b[36merr\x1b[0m=\x1b[31m\"oh no\"\x1b[0m " +
+		"\x1b[36mkey\x1b[0m=5\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, buf.String())
+	}
+
+	// Without a terminal or FORCE_COLOR, no colors are used.
+	t.Setenv("FORCE_COLOR", "")
+	buf.Reset()
+	log = NewSLogger(
+		NewDefaultHandler(&buf, WithColor(), WithNoTimestamp()),
+	)
+	log.Info("Plain")
+
+	if buf.String() != "[INF]: Plain\n" {
+		t.Fatalf("Unexpected output: %q", buf.String())
+	}
+}
@@ -0,0 +1,455 @@
+package btclog
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// defaultDedupWindow is the default duration for which identical records are
+// collapsed by a DedupHandler.
+const defaultDedupWindow = 10 * time.Second
+
+// DedupOption is the signature of a functional option that can be used to
+// modify the behaviour of a DedupHandler.
+type DedupOption func(*dedupOpts)
+
+// dedupOpts holds options that can be modified by a DedupOption.
+type dedupOpts struct {
+	// window is the duration, starting at the first of a series of
+	// identical records, for which any repetitions are collapsed.
+	window time.Duration
+
+	// ignoreAttrs holds the keys of attributes that are not taken into
+	// account when comparing records. Attributes in groups are identified
+	// by their dotted key, e.g. "peer.addr".
+	ignoreAttrs map[string]bool
+
+	// afterFunc starts a timer that calls the given function once the
+	// given duration has passed.
+	afterFunc func(time.Duration, func()) dedupTimer
+
+	// now is used to get the current time.
+	now func() time.Time
+}
+
+// dedupTimer is a timer that closes windows, as returned by the afterFunc
+// option.
+type dedupTimer interface {
+	// Reset changes the timer to fire once the given duration has passed.
+	Reset(d time.Duration) bool
+
+	// Stop prevents the timer from firing. It returns false if the timer
+	// has already fired or been stopped.
+	Stop() bool
+}
+
+// WithDedupWindow sets the duration for which repetitions of a record are
+// collapsed. The window starts when a record is logged. The default is ten
+// seconds.
+func WithDedupWindow(window time.Duration) DedupOption {
+	return func(o *dedupOpts) {
+		o.window = window
+	}
+}
+
+// WithDedupIgnoreAttrs instructs the DedupHandler to ignore the attributes
+// with the given keys when comparing records, so that records that only differ
+// in, for example, a counter or a timestamp attribute are still collapsed.
+// Attributes in groups are identified by their dotted key, e.g. "peer.addr".
+func WithDedupIgnoreAttrs(keys ...string) DedupOption {
+	return func(o *dedupOpts) {
+		for _, key := range keys {
+			o.ignoreAttrs[key] = true
+		}
+	}
+}
+
+// withDedupAfterFunc sets the function used to start the timer that closes a
+// window.
+func withDedupAfterFunc(
+	afterFunc func(time.Duration, func()) dedupTimer) DedupOption {
+
+	return func(o *dedupOpts) {
+		o.afterFunc = afterFunc
+	}
+}
+
+// withDedupClock sets the function used to get the current time.
+func withDedupClock(now func() time.Time) DedupOption {
+	return func(o *dedupOpts) {
+		o.now = now
+	}
+}
+
+// dedupState tracks the last record that was logged. It is shared by a
+// DedupHandler and all the Handlers derived from it so that repetitions are
+// detected across sub-systems in the same way as in a syslog.
+type dedupState struct {
+	opts *dedupOpts
+
+	mu sync.Mutex
+
+	// key identifies the last record that was logged. It is empty if no
+	// window is open.
+	key string
+
+	// handler is the Handler that logged the last record and that the
+	// summary is logged with.
+	handler Handler
+
+	// level is the level of the last record.
+	level slog.Level
+
+	// pc is the PC of the last record, which identifies its call-site.
+	pc uintptr
+
+	// repeated is the number of times the last record was repeated
+	// within the current window.
+	repeated uint64
+
+	// deadline is the time at which the current window closes.
+	deadline time.Time
+
+	// timer closes the current window. A single timer is created for the
+	// first window and reused for all later ones. It is only re-armed
+	// once it has fired, so that opening a window doesn't need a timer
+	// operation while the timer is still armed for an earlier deadline.
+	timer dedupTimer
+
+	// armed is true if the timer is set to fire.
+	armed bool
+}
+
+// DedupHandler is a Handler that collapses repetitions of a record. Each record
+// is passed on to a child Handler unless it is identical to the previous
+// record, in which case it is only counted. Two records are identical if they
+// have the same level, sub-system tag, message and attributes.
+//
+// Once the window of the first record closes, or once a different record is
+// logged, a single summary record such as "last message repeated 3,412 times"
+// is logged with the level, sub-system tag and call-site of the repeated
+// record.
+//
+// The shared state is only locked to decide which records to log. The records
+// themselves are passed on to the child Handler after the lock is released, so
+// that the I/O of one sub-system doesn't hold up the others. A summary is
+// logged by the goroutine whose record closes the window, right before that
+// record. Since records of other goroutines are passed on concurrently, a
+// summary may therefore appear after an unrelated record that was logged at
+// about the same time.
+type DedupHandler struct {
+	handler Handler
+	state   *dedupState
+
+	// tag is the sub-system tag of the handler.
+	tag string
+
+	// attrsKey identifies the attributes added with WithAttrs. It is part
+	// of the key of each record.
+	attrsKey string
+
+	// groupPrefix is the dotted prefix of the groups added with WithGroup.
+	groupPrefix string
+}
+
+// A compile-time check to ensure that DedupHandler implements Handler.
+var _ Handler = (*DedupHandler)(nil)
+
+// NewDedupHandler creates a new DedupHandler that passes the first of any
+// identical records on to the given Handler.
+func NewDedupHandler(handler Handler, options ...DedupOption) *DedupHandler {
+	opts := &dedupOpts{
+		window:      defaultDedupWindow,
+		ignoreAttrs: make(map[string]bool),
+		afterFunc: func(d time.Duration, f func()) dedupTimer {
+			return time.AfterFunc(d, f)
+		},
+		now: time.Now,
+	}
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &DedupHandler{
+		handler: handler,
+		state: &dedupState{
+			opts: opts,
+		},
+	}
+}
+
+// Level returns the current logging level of the child Handler.
+//
+// NOTE: This is part of the Handler interface.
+func (d *DedupHandler) Level() btclog.Level {
+	return d.handler.Level()
+}
+
+// SetLevel changes the logging level of the child Handler to the passed
+// level.
+//
+// NOTE: This is part of the Handler interface.
+func (d *DedupHandler) SetLevel(level btclog.Level) {
+	d.handler.SetLevel(level)
+}
+
+// Enabled reports whether the child Handler handles records at the given
+// level.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (d *DedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return d.handler.Enabled(ctx, level)
+}
+
+// Handle passes the Record on to the child Handler unless it is a repetition
+// of the previous record. If it is a different record, a summary of the
+// repetitions of the previous record is logged first.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (d *DedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	key := d.key(r)
+
+	s := d.state
+	s.mu.Lock()
+
+	if key == s.key {
+		s.repeated++
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	// A different record closes the current window.
+	summaryHandler, summary := s.summary()
+
+	s.key = key
+	s.handler = d.handler
+	s.level = r.Level
+	s.pc = r.PC
+	s.repeated = 0
+	s.deadline = s.opts.now().Add(s.opts.window)
+
+	// If the timer is still armed for the deadline of an earlier window,
+	// it is re-armed for this one once it fires.
+	switch {
+	case s.timer == nil:
+		s.timer = s.opts.afterFunc(s.opts.window, s.expire)
+		s.armed = true
+
+	case !s.armed:
+		s.timer.Reset(s.opts.window)
+		s.armed = true
+	}
+
+	s.mu.Unlock()
+
+	var err error
+	if summaryHandler != nil {
+		err = summaryHandler.Handle(context.Background(), summary)
+	}
+	if hErr := d.handler.Handle(ctx, r); err == nil {
+		err = hErr
+	}
+
+	return err
+}
+
+// Flush logs the summary of the current window, if there were any repetitions,
+// and closes the window. It can be used to make sure that no summary is lost
+// when shutting down.
+func (d *DedupHandler) Flush() error {
+	s := d.state
+	s.mu.Lock()
+
+	// If the timer can't be stopped, it is about to fire and will find
+	// the window closed.
+	if s.armed && s.timer.Stop() {
+		s.armed = false
+	}
+
+	handler, summary := s.summary()
+	s.key, s.handler = "", nil
+	s.mu.Unlock()
+
+	if handler == nil {
+		return nil
+	}
+
+	return handler.Handle(context.Background(), summary)
+}
+
+// WithAttrs returns a new Handler with the given attributes added.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (d *DedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h := d.handler.WithAttrs(attrs)
+	handler, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	var sb strings.Builder
+	sb.WriteString(d.attrsKey)
+	for _, a := range attrs {
+		d.appendKeyAttr(&sb, d.groupPrefix, a)
+	}
+
+	return d.with(handler, d.tag, sb.String(), d.groupPrefix)
+}
+
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (d *DedupHandler) WithGroup(name string) slog.Handler {
+	h := d.handler.WithGroup(name)
+	handler, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return d.with(handler, d.tag, d.attrsKey, d.groupPrefix+name+".")
+}
+
+// SubSystem returns a copy of the given handler but with the new tag. The
+// returned Handler shares its state with the receiver so that repetitions are
+// detected across all sub-systems.
+//
+// NOTE: This is part of the Handler interface.
+func (d *DedupHandler) SubSystem(tag string) Handler {
+	return d.with(d.handler.SubSystem(tag), tag, d.attrsKey, d.groupPrefix)
+}
+
+// with returns a new DedupHandler that shares the state of the receiver.
+func (d *DedupHandler) with(handler Handler, tag, attrsKey,
+	groupPrefix string) *DedupHandler {
+
+	return &DedupHandler{
+		handler:     handler,
+		state:       d.state,
+		tag:         tag,
+		attrsKey:    attrsKey,
+		groupPrefix: groupPrefix,
+	}
+}
+
+// key returns a string that identifies the given record along with the tag
+// and attributes of the handler.
+func (d *DedupHandler) key(r slog.Record) string {
+	var sb strings.Builder
+	sb.WriteString(d.tag)
+	sb.WriteByte(0)
+	sb.WriteString(d.attrsKey)
+	sb.WriteByte(0)
+	sb.WriteString(r.Level.String())
+	sb.WriteByte(0)
+	sb.WriteString(r.Message)
+
+	r.Attrs(func(a slog.Attr) bool {
+		d.appendKeyAttr(&sb, d.groupPrefix, a)
+		return true
+	})
+
+	return sb.String()
+}
+
+// appendKeyAttr appends the given attribute to a record key unless it is one
+// of the ignored attributes. Groups are expanded so that their attributes can
+// be ignored individually.
+func (d *DedupHandler) appendKeyAttr(sb *strings.Builder, prefix string,
+	a slog.Attr) {
+
+	a.Value = a.Value.Resolve()
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			d.appendKeyAttr(sb, prefix, ga)
+		}
+
+		return
+	}
+
+	key := prefix + a.Key
+	if d.state.opts.ignoreAttrs[key] {
+		return
+	}
+
+	sb.WriteByte(0)
+	sb.WriteString(key)
+	sb.WriteByte('=')
+	sb.WriteString(a.Value.String())
+}
+
+// expire is called by the timer. It closes the current window and logs its
+// summary if the window's deadline has passed, and re-arms the timer for the
+// deadline otherwise.
+func (s *dedupState) expire() {
+	s.mu.Lock()
+
+	if s.key == "" {
+		s.armed = false
+		s.mu.Unlock()
+
+		return
+	}
+
+	// The window that the timer was armed for was closed by a different
+	// record and a newer window is open.
+	if now := s.opts.now(); now.Before(s.deadline) {
+		s.timer.Reset(s.deadline.Sub(now))
+		s.mu.Unlock()
+
+		return
+	}
+
+	handler, summary := s.summary()
+	s.key, s.handler, s.armed = "", nil, false
+	s.mu.Unlock()
+
+	if handler != nil {
+		_ = handler.Handle(context.Background(), summary)
+	}
+}
+
+// summary returns the Handler to log the summary of the repetitions of the last
+// record with, along with the summary record, and resets the count. A nil
+// Handler is returned if there were no repetitions. The caller must hold the
+// mutex and log the summary once it has been released.
+func (s *dedupState) summary() (Handler, slog.Record) {
+	if s.repeated == 0 {
+		return nil, slog.Record{}
+	}
+
+	msg := "last message repeated " + formatCount(s.repeated) + " times"
+	if s.repeated == 1 {
+		msg = "last message repeated 1 time"
+	}
+	r := slog.NewRecord(time.Now(), s.level, msg, s.pc)
+	s.repeated = 0
+
+	return s.handler, r
+}
+
+// formatCount formats n with a comma as the thousands separator.
+func formatCount(n uint64) string {
+	digits := strconv.FormatUint(n, 10)
+
+	var sb strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(c)
+	}
+
+	return sb.String()
+}
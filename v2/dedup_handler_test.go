@@ -0,0 +1,171 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeTimer is a dedupTimer that only fires when the test calls fire.
+type fakeTimer struct {
+	fire   func()
+	armed  bool
+	resets []time.Duration
+}
+
+// Reset arms the timer and records the duration.
+func (f *fakeTimer) Reset(d time.Duration) bool {
+	armed := f.armed
+	f.armed = true
+	f.resets = append(f.resets, d)
+
+	return armed
+}
+
+// Stop disarms the timer.
+func (f *fakeTimer) Stop() bool {
+	armed := f.armed
+	f.armed = false
+
+	return armed
+}
+
+// TestDedupHandler tests that repetitions of a record are collapsed and that
+// a summary is logged once a different record arrives.
+func TestDedupHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	dedup := NewDedupHandler(
+		NewDefaultHandler(&buf, WithNoTimestamp()),
+		WithDedupWindow(time.Hour), WithDedupIgnoreAttrs("req.id"),
+	)
+	ctx := context.Background()
+
+	peer := NewSLogger(dedup.SubSystem("PEER"))
+	srvr := NewSLogger(dedup.SubSystem("SRVR"))
+
+	for i := 0; i < 1235; i++ {
+		peer.WarnS(ctx, "Misbehaving peer", nil, "score", 10,
+			slog.Group("req", "id", i))
+	}
+
+	// The same message from a different sub-system is a different record.
+	srvr.WarnS(ctx, "Misbehaving peer", nil, "score", 10)
+	srvr.WarnS(ctx, "Misbehaving peer", nil, "score", 11)
+	srvr.WarnS(ctx, "Misbehaving peer", nil, "score", 11)
+
+	if err := dedup.Flush(); err != nil {
+		t.Fatalf("Unable to flush: %v", err)
+	}
+
+	expected := `[WRN] PEER: Misbehaving peer score=10 req.id=0
+[WRN] PEER: last message repeated 1,234 times
+[WRN] SRVR: Misbehaving peer score=10
+[WRN] SRVR: Misbehaving peer score=11
+[WRN] SRVR: last message repeated 1 time
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+// TestDedupHandlerWindow tests that the summary is logged once the window
+// closes, that the next repetition is logged again and that a single timer is
+// used for all windows, which is only re-armed once it fires.
+func TestDedupHandlerWindow(t *testing.T) {
+	t.Parallel()
+
+	var timers []*fakeTimer
+	afterFunc := func(_ time.Duration, f func()) dedupTimer {
+		timer := &fakeTimer{fire: f, armed: true}
+		timers = append(timers, timer)
+
+		return timer
+	}
+
+	now := time.Date(2024, 10, 3, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		return now
+	}
+
+	var buf bytes.Buffer
+	dedup := NewDedupHandler(
+		NewDefaultHandler(&buf, WithNoTimestamp()),
+		WithDedupWindow(time.Minute), withDedupAfterFunc(afterFunc),
+		withDedupClock(clock),
+	)
+	log := NewSLogger(dedup)
+
+	// The timer fires at the end of the first window.
+	log.Info("Repeated")
+	log.Info("Repeated")
+	log.Info("Repeated")
+	now = now.Add(time.Minute)
+	timers[0].armed = false
+	timers[0].fire()
+
+	// The timer is re-armed for the next window, which is closed by a
+	// different record after a second.
+	log.Info("Repeated")
+	log.Info("Repeated")
+	now = now.Add(time.Second)
+	log.Info("Other")
+
+	// The timer fires at the deadline of the closed window and is re-armed
+	// for the remainder of the current one.
+	now = now.Add(time.Minute - time.Second)
+	timers[0].armed = false
+	timers[0].fire()
+	log.Info("Other")
+
+	now = now.Add(time.Second)
+	timers[0].armed = false
+	timers[0].fire()
+
+	if len(timers) != 1 {
+		t.Fatalf("Expected a single timer, got %d", len(timers))
+	}
+	expectedResets := []time.Duration{time.Minute, time.Second}
+	if !reflect.DeepEqual(timers[0].resets, expectedResets) {
+		t.Fatalf("Expected resets %v, got %v", expectedResets,
+			timers[0].resets)
+	}
+
+	// All windows are closed, so there is nothing left to flush.
+	if err := dedup.Flush(); err != nil {
+		t.Fatalf("Unable to flush: %v", err)
+	}
+
+	expected := `[INF]: Repeated
+[INF]: last message repeated 2 times
+[INF]: Repeated
+[INF]: last message repeated 1 time
+[INF]: Other
+[INF]: last message repeated 1 time
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+// TestFormatCount tests that counts are formatted with thousands separators.
+func TestFormatCount(t *testing.T) {
+	t.Parallel()
+
+	tests := map[uint64]string{
+		0:        "0",
+		999:      "999",
+		1000:     "1,000",
+		3412:     "3,412",
+		12345678: "12,345,678",
+	}
+	for n, expected := range tests {
+		if got := formatCount(n); got != expected {
+			t.Fatalf("Expected %s, got %s", expected, got)
+		}
+	}
+}
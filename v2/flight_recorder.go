@@ -0,0 +1,256 @@
+package btclog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/btcsuite/btclog"
+)
+
+// defaultFlightRecorderSize is the default maximum number of records that are
+// held back per context.
+const defaultFlightRecorderSize = 1000
+
+type flightRecorderKey struct{}
+
+// flightRecord is a record that was held back by a flight recorder along with
+// the Handler that it will be passed to if it is flushed.
+type flightRecord struct {
+	handler Handler
+	record  slog.Record
+}
+
+// replay passes the held back record on to its Handler with a context that
+// enables the record's level, provided that the Handler accepts it.
+func (fr *flightRecord) replay(ctx context.Context) error {
+	level := fr.record.Level
+	ctx = WithCtxLevel(ctx, fromSlogLevel(level))
+	if !fr.handler.Enabled(ctx, level) {
+		return nil
+	}
+
+	return fr.handler.Handle(ctx, fr.record)
+}
+
+// flightRecorder holds back the records of a single context.
+type flightRecorder struct {
+	mu      sync.Mutex
+	max     int
+	records []flightRecord
+	done    bool
+}
+
+// add holds back the given record, dropping the oldest one if the recorder is
+// full. Records are dropped if the context has already finished.
+func (f *flightRecorder) add(handler Handler, r slog.Record) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.done {
+		return
+	}
+
+	if len(f.records) == f.max {
+		copy(f.records, f.records[1:])
+		f.records = f.records[:len(f.records)-1]
+	}
+
+	f.records = append(f.records, flightRecord{
+		handler: handler,
+		record:  r.Clone(),
+	})
+}
+
+// take removes and returns all records that are held back.
+func (f *flightRecorder) take() []flightRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := f.records
+	f.records = nil
+
+	return records
+}
+
+// discard drops all records that are held back along with any that are added
+// later.
+func (f *flightRecorder) discard() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records = nil
+	f.done = true
+}
+
+// WithFlightRecorder returns a copy of the context for which records below the
+// level of a FlightRecorderHandler are held back in memory rather than being
+// dropped. If a record at LevelError or above is logged with the context, the
+// held back records are logged right before it, in the order in which they were
+// recorded. Otherwise they are discarded once the context is done. At most max
+// records are held back, with the oldest being dropped first. If max is not
+// positive, a default of 1000 is used.
+//
+// Usage:
+//
+//	ctx := log.WithFlightRecorder(ctx, 100)
+//	ctx = log.WithCtx(ctx, "request", id)
+//	...
+//	log.DebugS(ctx, "Fetched UTXOs")     // Held back.
+//	log.ErrorS(ctx, "Request failed", err) // Logged after the debug record.
+func WithFlightRecorder(ctx context.Context, max int) context.Context {
+	if max <= 0 {
+		max = defaultFlightRecorderSize
+	}
+
+	f := &flightRecorder{max: max}
+	context.AfterFunc(ctx, f.discard)
+
+	return context.WithValue(ctx, flightRecorderKey{}, f)
+}
+
+// flightRecorderFromCtx returns the flight recorder of the context, if any.
+func flightRecorderFromCtx(ctx context.Context) (*flightRecorder, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	f, ok := ctx.Value(flightRecorderKey{}).(*flightRecorder)
+
+	return f, ok
+}
+
+// FlightRecorderHandler is a Handler that holds back the records of contexts
+// that were created with WithFlightRecorder if they are below the level of the
+// child Handler. The held back records of a context are passed on to the child
+// Handler, before the error itself, as soon as a record at LevelError or above
+// is logged with that context. This way production systems can run at
+// LevelInfo while still getting the debug context of a failed request.
+// Records of contexts without a flight recorder are handled as usual.
+//
+// Records that are at or above the child's level are written right away, so
+// the replayed records appear after any newer records of the context that were
+// already written. They keep their original timestamps and call-sites, which
+// can be used to tell where they belong.
+//
+// The replayed records are passed to the child with a context that has their
+// own level associated with it via WithCtxLevel. The child therefore still
+// decides whether they are logged, which the DefaultHandler, the JSONHandler
+// and a MultiHandler of them do unless their level is LevelOff. Records are
+// not replayed to a child that ignores the context level.
+type FlightRecorderHandler struct {
+	handler Handler
+}
+
+// A compile-time check to ensure that FlightRecorderHandler implements
+// Handler.
+var _ Handler = (*FlightRecorderHandler)(nil)
+
+// NewFlightRecorderHandler creates a new FlightRecorderHandler that passes
+// records on to the given Handler.
+func NewFlightRecorderHandler(handler Handler) *FlightRecorderHandler {
+	return &FlightRecorderHandler{
+		handler: handler,
+	}
+}
+
+// Level returns the current logging level of the child Handler.
+//
+// NOTE: This is part of the Handler interface.
+func (f *FlightRecorderHandler) Level() btclog.Level {
+	return f.handler.Level()
+}
+
+// SetLevel changes the logging level of the child Handler to the passed
+// level.
+//
+// NOTE: This is part of the Handler interface.
+func (f *FlightRecorderHandler) SetLevel(level btclog.Level) {
+	f.handler.SetLevel(level)
+}
+
+// Enabled reports whether the child Handler handles records at the given level
+// or whether the context has a flight recorder that records are held back in.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (f *FlightRecorderHandler) Enabled(ctx context.Context,
+	level slog.Level) bool {
+
+	if f.handler.Enabled(ctx, level) {
+		return true
+	}
+
+	_, ok := flightRecorderFromCtx(ctx)
+
+	return ok
+}
+
+// Handle passes the Record on to the child Handler if it is enabled for the
+// Record's level and holds it back otherwise. Any records that were held back
+// for the context are replayed first if the Record is an error.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (f *FlightRecorderHandler) Handle(ctx context.Context,
+	r slog.Record) error {
+
+	// The recorder of a context that is done is discarded right away
+	// rather than waiting for the call-back registered in
+	// WithFlightRecorder to run.
+	recorder, ok := flightRecorderFromCtx(ctx)
+	if ok && ctx.Err() != nil {
+		recorder.discard()
+		ok = false
+	}
+
+	if !f.handler.Enabled(ctx, r.Level) {
+		if ok {
+			recorder.add(f.handler, r)
+		}
+
+		return nil
+	}
+
+	if ok && r.Level >= levelError {
+		for _, fr := range recorder.take() {
+			if err := fr.replay(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return f.handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a new Handler with the given attributes added.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (f *FlightRecorderHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h := f.handler.WithAttrs(attrs)
+	handler, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return NewFlightRecorderHandler(handler)
+}
+
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (f *FlightRecorderHandler) WithGroup(name string) slog.Handler {
+	h := f.handler.WithGroup(name)
+	handler, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return NewFlightRecorderHandler(handler)
+}
+
+// SubSystem returns a copy of the given handler but with the new tag.
+//
+// NOTE: This is part of the Handler interface.
+func (f *FlightRecorderHandler) SubSystem(tag string) Handler {
+	return NewFlightRecorderHandler(f.handler.SubSystem(tag))
+}
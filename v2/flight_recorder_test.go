@@ -0,0 +1,98 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestFlightRecorderHandler tests that records below the handler's level are
+// only logged, before the error, if an error is logged for their context.
+func TestFlightRecorderHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	h := NewFlightRecorderHandler(
+		NewDefaultHandler(&buf, WithNoTimestamp()),
+	)
+	log := NewSLogger(h.SubSystem("RPCS"))
+	log.SetLevel(LevelInfo)
+
+	// Records of a context without a flight recorder are dropped.
+	log.DebugS(context.Background(), "Not recorded")
+
+	// A request that fails.
+	failed, cancelFailed := context.WithCancel(context.Background())
+	defer cancelFailed()
+	failed = WithCtx(WithFlightRecorder(failed, 2), "req", 1)
+
+	log.TraceS(failed, "Dropped since the recorder is full")
+	log.DebugS(failed, "Fetching block")
+	log.InfoS(failed, "Request started")
+	log.TraceS(failed, "Fetched block")
+	log.ErrorS(failed, "Request failed", errors.New("boom"))
+
+	// A request that succeeds.
+	ok, cancelOK := context.WithCancel(context.Background())
+	ok = WithCtx(WithFlightRecorder(ok, 0), "req", 2)
+	log.DebugS(ok, "Fetching block")
+	cancelOK()
+
+	// Once the context is done, nothing is recorded anymore.
+	log.DebugS(ok, "After done")
+	log.ErrorS(ok, "Late error", nil)
+
+	// The held back records are replayed in order right before the error,
+	// so they appear after the info record that was written right away.
+	expected := `[INF] RPCS: Request started req=1
+[DBG] RPCS: Fetching block req=1
+[TRC] RPCS: Fetched block req=1
+[ERR] RPCS: Request failed req=1 err=boom
+[ERR] RPCS: Late error req=2
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+// TestFlightRecorderMultiHandler tests that held back records are replayed to
+// all handlers of a MultiHandler child, except for those that are off.
+func TestFlightRecorderMultiHandler(t *testing.T) {
+	t.Parallel()
+
+	var textBuf, jsonBuf, offBuf bytes.Buffer
+	off := NewDefaultHandler(&offBuf, WithNoTimestamp())
+	off.SetLevel(LevelOff)
+
+	h := NewFlightRecorderHandler(NewMultiHandler(
+		NewDefaultHandler(&textBuf, WithNoTimestamp()),
+		NewJSONHandler(&jsonBuf, WithNoTimestamp()),
+		off,
+	))
+	log := NewSLogger(h)
+
+	ctx := WithFlightRecorder(context.Background(), 0)
+	log.DebugS(ctx, "Fetching block")
+	log.ErrorS(ctx, "Request failed", nil)
+
+	expectedText := `[DBG]: Fetching block
+[ERR]: Request failed
+`
+	if textBuf.String() != expectedText {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expectedText,
+			textBuf.String())
+	}
+
+	expectedJSON := `{"level":"DBG","msg":"Fetching block"}
+{"level":"ERR","msg":"Request failed"}
+`
+	if jsonBuf.String() != expectedJSON {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expectedJSON,
+			jsonBuf.String())
+	}
+
+	if offBuf.Len() != 0 {
+		t.Fatalf("Expected no output, got:\n%s", offBuf.String())
+	}
+}
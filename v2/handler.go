@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -33,7 +34,9 @@ type handlerOpts struct {
 
 	// callSiteSkipDepth is the number of stack frames to ascend when
 	// determining the call site of a log. Users of this package may want
-	// to alter this depth depending on if they wrap the logger at all.
+	// to alter this depth depending on if they wrap the logger at all. If
+	// it is zero, the call site is taken from the PC of the record
+	// instead.
 	callSiteSkipDepth int
 
 	// styledLevel is a call-back that can be used to determine how the log
@@ -47,14 +50,35 @@ type handlerOpts struct {
 	// styledKey is a call-back that can be used to determine how any key
 	// in an attributes key-value pair will appear when printed.
 	styledKey func(string) string
+
+	// styledTimestamp is an optional call-back that can be used to
+	// determine how the timestamp will appear when printed.
+	styledTimestamp func(string) string
+
+	// styledValue is an optional call-back that can be used to determine
+	// how the value in an attributes key-value pair will appear when
+	// printed. It is given the unquoted key and the formatted value.
+	styledValue func(key, value string) string
+
+	// colorTheme is the theme to apply to the output if the writer
+	// supports colors.
+	colorTheme *ColorTheme
+
+	// groupsAsTag defines whether groups added with WithGroup should be
+	// appended to the sub-system tag instead of qualifying the keys of the
+	// attributes that follow.
+	groupsAsTag bool
+
+	// ctxExtractors are called for each record to derive additional
+	// attributes from the context that the record was logged with.
+	ctxExtractors []ContextExtractor
 }
 
 // defaultHandlerOpts constructs a handlerOpts with default settings.
 func defaultHandlerOpts() *handlerOpts {
 	return &handlerOpts{
-		flag:              defaultFlags,
-		withTimestamp:     true,
-		callSiteSkipDepth: 6,
+		flag:          defaultFlags,
+		withTimestamp: true,
 		styledLevel: func(level btclog.Level) string {
 			return fmt.Sprintf("[%s]", level)
 		},
@@ -82,7 +106,14 @@ func WithTimeSource(fn func() time.Time) HandlerOption {
 	}
 }
 
-// WithCallSiteSkipDepth can be used to set the call-site skip depth.
+// WithCallSiteSkipDepth can be used to set the call-site skip depth. By
+// default, the call-site is taken from the PC of the record, which a Logger
+// created with NewSLogger sets to the caller of its logging methods, no matter
+// how many Handlers the record passes through. If a skip depth is set, the
+// call-site is instead found by ascending the call stack, where a depth of 6
+// is the caller of a Logger method. This can be used to skip helper functions
+// that wrap a Logger, but note that each wrapping Handler, such as a
+// MultiHandler, adds another frame.
 func WithCallSiteSkipDepth(depth int) HandlerOption {
 	return func(opts *handlerOpts) {
 		opts.callSiteSkipDepth = depth
@@ -112,6 +143,23 @@ func WithStyledKeys(fn func(string) string) HandlerOption {
 	}
 }
 
+// WithStyledTimestamp can be used adjust the timestamp string before it is
+// printed.
+func WithStyledTimestamp(fn func(string) string) HandlerOption {
+	return func(opts *handlerOpts) {
+		opts.styledTimestamp = fn
+	}
+}
+
+// WithStyledValues can be used adjust the value strings for any key-value
+// attribute pair. The call-back is given the unquoted key along with the
+// formatted value.
+func WithStyledValues(fn func(key, value string) string) HandlerOption {
+	return func(opts *handlerOpts) {
+		opts.styledValue = fn
+	}
+}
+
 // WithNoTimestamp is an option that can be used to omit timestamps from the log
 // lines.
 func WithNoTimestamp() HandlerOption {
@@ -120,15 +168,39 @@ func WithNoTimestamp() HandlerOption {
 	}
 }
 
+// WithGroupsAsTag can be used to restore the legacy behaviour of WithGroup in
+// which the group name is appended to the sub-system tag rather than being used
+// to qualify the keys of the attributes that follow.
+func WithGroupsAsTag() HandlerOption {
+	return func(opts *handlerOpts) {
+		opts.groupsAsTag = true
+	}
+}
+
+// WithContextExtractor adds a ContextExtractor that is called for each record
+// to derive additional attributes, such as a trace ID, from the context that
+// the record was logged with. The attributes are added in front of the
+// record's own attributes, in the order that the extractors were added. They
+// are in addition to any attributes stored in the context with WithCtx.
+func WithContextExtractor(fn ContextExtractor) HandlerOption {
+	return func(opts *handlerOpts) {
+		opts.ctxExtractors = append(opts.ctxExtractors, fn)
+	}
+}
+
 // DefaultHandler is a Handler that can be used along with NewSLogger to
 // instantiate a structured logger.
 type DefaultHandler struct {
 	opts *handlerOpts
 
-	level           int64
-	tag             string
-	fields          []slog.Attr
-	callstackOffset bool
+	level  int64
+	tag    string
+	fields []slog.Attr
+
+	// groupPrefix is the dot separated list of groups added with
+	// WithGroup, including a trailing dot. It is used to qualify the keys
+	// of any attributes added after the groups.
+	groupPrefix string
 
 	flag uint32
 	buf  *buffer
@@ -162,6 +234,10 @@ func NewDefaultHandler(w io.Writer, options ...HandlerOption) *DefaultHandler {
 		o(opts)
 	}
 
+	if opts.colorTheme != nil && ColorEnabled(w) {
+		opts.colorTheme.apply(opts)
+	}
+
 	return &DefaultHandler{
 		w:     w,
 		level: int64(levelInfo),
@@ -172,16 +248,25 @@ func NewDefaultHandler(w io.Writer, options ...HandlerOption) *DefaultHandler {
 }
 
 // Enabled reports whether the handler handles records at the given level.
+// Records below the handler's level are still handled if the context has a
+// lower level associated with it via WithCtxLevel.
 //
 // NOTE: this is part of the slog.Handler interface.
-func (d *DefaultHandler) Enabled(_ context.Context, level slog.Level) bool {
-	return atomic.LoadInt64(&d.level) <= int64(level)
+func (d *DefaultHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	handlerLevel := slog.Level(atomic.LoadInt64(&d.level))
+	if handlerLevel <= level {
+		return true
+	}
+
+	return ctxLevelEnabled(ctx, handlerLevel, level)
 }
 
 // Handle handles the Record. It will only be called if Enabled returns true.
 //
 // NOTE: this is part of the slog.Handler interface.
-func (d *DefaultHandler) Handle(_ context.Context, r slog.Record) error {
+func (d *DefaultHandler) Handle(ctx context.Context, r slog.Record) error {
+	r = extractCtxAttrs(ctx, r, d.opts.ctxExtractors)
+
 	buf := newBuffer()
 	defer buf.free()
 
@@ -190,9 +275,9 @@ func (d *DefaultHandler) Handle(_ context.Context, r slog.Record) error {
 		// First check if the options provided specified a different
 		// time source to use. Otherwise, use the provided record time.
 		if d.opts.timeSource != nil {
-			writeTimestamp(buf, d.opts.timeSource())
+			d.writeTimestamp(buf, d.opts.timeSource())
 		} else if !r.Time.IsZero() {
-			writeTimestamp(buf, r.Time)
+			d.writeTimestamp(buf, r.Time)
 		}
 	}
 
@@ -205,13 +290,10 @@ func (d *DefaultHandler) Handle(_ context.Context, r slog.Record) error {
 	}
 
 	// The call-site.
-	skipBase := d.opts.callSiteSkipDepth
 	if d.opts.flag&(Lshortfile|Llongfile) != 0 {
-		skip := skipBase
-		if d.callstackOffset && skip >= 2 {
-			skip -= 2
-		}
-		file, line := callsite(d.opts.flag, skip)
+		file, line := callsite(
+			d.opts.flag, d.opts.callSiteSkipDepth, r.PC,
+		)
 		d.writeCallSite(buf, file, line)
 	}
 
@@ -223,16 +305,7 @@ func (d *DefaultHandler) Handle(_ context.Context, r slog.Record) error {
 		buf.writeString(r.Message)
 	}
 
-	// Append logger fields.
-	for _, attr := range d.fields {
-		d.appendAttr(buf, attr)
-	}
-
-	// Append slog attributes.
-	r.Attrs(func(a slog.Attr) bool {
-		d.appendAttr(buf, a)
-		return true
-	})
+	d.appendAttrs(buf, r)
 	buf.writeByte('\n')
 
 	d.mu.Lock()
@@ -246,35 +319,53 @@ func (d *DefaultHandler) Handle(_ context.Context, r slog.Record) error {
 //
 // NOTE: this is part of the slog.Handler interface.
 func (d *DefaultHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return d.with(d.tag, true, attrs...)
+	// Any attributes added after a call to WithGroup are wrapped in a
+	// group so that their keys are qualified by the group names.
+	if d.groupPrefix != "" && len(attrs) > 0 {
+		attrs = []slog.Attr{{
+			Key:   strings.TrimSuffix(d.groupPrefix, "."),
+			Value: slog.GroupValue(attrs...),
+		}}
+	}
+
+	return d.with(d.tag, attrs...)
 }
 
-// WithGroup returns a new Handler with the given group appended to
-// the receiver's existing groups. All this implementation does is that it adds
-// to the existing tag used for the logger.
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups. The keys of any attributes added after this call
+// will be qualified by the group name, i.e. `group.key=value`. If the
+// WithGroupsAsTag option was used, the group name is instead appended to the
+// existing tag used for the logger.
 //
 // NOTE: this is part of the slog.Handler interface.
 func (d *DefaultHandler) WithGroup(name string) slog.Handler {
-	if d.tag != "" {
-		name = d.tag + "." + name
+	if d.opts.groupsAsTag {
+		if d.tag != "" {
+			name = d.tag + "." + name
+		}
+		return d.with(name)
 	}
-	return d.with(name, true)
+
+	sl := d.with(d.tag)
+	if name != "" {
+		sl.groupPrefix += name + "."
+	}
+
+	return sl
 }
 
 // SubSystem returns a copy of the given handler but with the new tag. All
-// attributes added with WithAttrs will be kept but all groups added with
-// WithGroup are lost.
+// attributes added with WithAttrs and all groups added with WithGroup will be
+// kept, unless the WithGroupsAsTag option was used in which case the groups are
+// lost.
 //
 // NOTE: this is part of the Handler interface.
 func (d *DefaultHandler) SubSystem(tag string) Handler {
-	return d.with(tag, false)
+	return d.with(tag)
 }
 
 // with returns a new logger with the given attributes added.
-// withCallstackOffset should be false if the caller returns a concrete
-// DefaultHandler and true if the caller returns the Handler interface.
-func (d *DefaultHandler) with(tag string, withCallstackOffset bool,
-	attrs ...slog.Attr) *DefaultHandler {
+func (d *DefaultHandler) with(tag string, attrs ...slog.Attr) *DefaultHandler {
 
 	d.mu.Lock()
 	sl := *d
@@ -286,15 +377,30 @@ func (d *DefaultHandler) with(tag string, withCallstackOffset bool,
 		make([]slog.Attr, 0, len(d.fields)+len(attrs)), d.fields...,
 	)
 	sl.fields = append(sl.fields, attrs...)
-	sl.callstackOffset = withCallstackOffset
 	sl.tag = tag
 
 	return &sl
 }
 
+// appendAttrs writes the attributes added with WithAttrs followed by those of
+// the given record to the buffer.
+func (d *DefaultHandler) appendAttrs(buf *buffer, r slog.Record) {
+	// Append logger fields.
+	for _, attr := range d.fields {
+		d.appendAttr(buf, "", attr)
+	}
+
+	// Append slog attributes.
+	r.Attrs(func(a slog.Attr) bool {
+		d.appendAttr(buf, d.groupPrefix, a)
+		return true
+	})
+}
+
 // appendAttr extracts a key-value pair from the slog.Attr and writes it to the
-// buffer.
-func (d *DefaultHandler) appendAttr(buf *buffer, a slog.Attr) {
+// buffer. The key is qualified by the given group prefix. Group values are
+// expanded recursively with each group name added to the prefix.
+func (d *DefaultHandler) appendAttr(buf *buffer, prefix string, a slog.Attr) {
 	// Resolve the Attr's value before doing anything else.
 	a.Value = a.Value.Resolve()
 
@@ -303,8 +409,49 @@ func (d *DefaultHandler) appendAttr(buf *buffer, a slog.Attr) {
 		return
 	}
 
-	d.appendKey(buf, a.Key)
-	appendValue(buf, a.Value)
+	if a.Value.Kind() == slog.KindGroup {
+		// Groups with empty keys are inlined.
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			d.appendAttr(buf, prefix, ga)
+		}
+
+		return
+	}
+
+	d.appendKey(buf, prefix+a.Key)
+
+	if d.opts.styledValue == nil {
+		appendValue(buf, a.Value)
+		return
+	}
+
+	valBuf := newBuffer()
+	defer valBuf.free()
+
+	appendValue(valBuf, a.Value)
+	buf.writeString(d.opts.styledValue(prefix+a.Key, string(*valBuf)))
+}
+
+// writeTimestamp writes the given time to the buffer, styled if a timestamp
+// style call-back is set.
+func (d *DefaultHandler) writeTimestamp(buf *buffer, t time.Time) {
+	if d.opts.styledTimestamp == nil {
+		writeTimestamp(buf, t)
+		return
+	}
+
+	tsBuf := newBuffer()
+	defer tsBuf.free()
+
+	writeTimestamp(tsBuf, t)
+
+	// Only style the timestamp itself and not its trailing space.
+	ts := strings.TrimSuffix(string(*tsBuf), " ")
+	buf.writeString(d.opts.styledTimestamp(ts))
+	buf.writeByte(' ')
 }
 
 // writeLevel writes the given slog.Level to the buffer in its string form.
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/btcsuite/btclog"
 	"io"
+	"log/slog"
 	"testing"
 	"time"
 )
@@ -55,7 +56,7 @@ func TestDefaultHandler(t *testing.T) {
 			logFunc: func(log Logger) {
 				log.Info("Test Basic Log")
 			},
-			expectedLog: `[INF] handler_test.go:196: Test Basic Log
+			expectedLog: `[INF] handler_test.go:197: Test Basic Log
 `,
 		},
 		{
@@ -203,3 +204,77 @@ func TestDefaultHandler(t *testing.T) {
 		})
 	}
 }
+
+// TestDefaultHandlerGroups tests that groups added with WithGroup and group
+// attribute values are rendered with dotted keys.
+func TestDefaultHandlerGroups(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	h := NewDefaultHandler(&buf, WithNoTimestamp()).SubSystem("SUBS")
+
+	logger := slog.New(h).With("a", 1).WithGroup("g").With("b", 2).
+		WithGroup("h")
+
+	logger.Info("Groups", "c", 3, slog.Group("i", "d", 4,
+		slog.Group("", "e", 5), slog.Group("empty")))
+
+	// The legacy behaviour appends the group to the tag instead.
+	h = NewDefaultHandler(
+		&buf, WithNoTimestamp(), WithGroupsAsTag(),
+	).SubSystem("SUBS")
+	slog.New(h).WithGroup("g").Info("Legacy", "c", 3)
+
+	expected := `[INF] SUBS: Groups a=1 g.b=2 g.h.c=3 g.h.i.d=4 g.h.i.e=5
+[INF] SUBS.g: Legacy c=3
+`
+	if buf.String() != expected {
+		t.Fatalf("Log result mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expected, buf.String())
+	}
+}
+
+// TestCtxLevel tests that a level associated with a context enables records
+// below the handler's level for that context only, unless the handler is off.
+func TestCtxLevel(t *testing.T) {
+	t.Parallel()
+
+	var textBuf, jsonBuf bytes.Buffer
+	log := NewSLogger(NewMultiHandler(
+		NewDefaultHandler(&textBuf, WithNoTimestamp()),
+		NewJSONHandler(&jsonBuf, WithNoTimestamp()),
+	).SubSystem("RPCS"))
+	log.SetLevel(LevelInfo)
+
+	ctx := context.Background()
+	traceCtx := WithCtxLevel(WithCtx(ctx, "req", 1), LevelTrace)
+	warnCtx := WithCtxLevel(ctx, LevelWarn)
+
+	log.TraceS(ctx, "Not logged")
+	log.TraceS(traceCtx, "Trace")
+	log.DebugS(traceCtx, "Debug")
+	log.InfoS(warnCtx, "Info")
+
+	// A context level doesn't enable records of a handler that is off.
+	log.SetLevel(LevelOff)
+	log.TraceS(traceCtx, "Off")
+	log.CriticalS(traceCtx, "Off", nil)
+
+	expectedText := `[TRC] RPCS: Trace req=1
+[DBG] RPCS: Debug req=1
+[INF] RPCS: Info
+`
+	if textBuf.String() != expectedText {
+		t.Fatalf("Text result mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedText, textBuf.String())
+	}
+
+	expectedJSON := `{"level":"TRC","subsystem":"RPCS","msg":"Trace","req":1}
+{"level":"DBG","subsystem":"RPCS","msg":"Debug","req":1}
+{"level":"INF","subsystem":"RPCS","msg":"Info"}
+`
+	if jsonBuf.String() != expectedJSON {
+		t.Fatalf("JSON result mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedJSON, jsonBuf.String())
+	}
+}
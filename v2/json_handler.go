@@ -0,0 +1,452 @@
+package btclog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btclog"
+)
+
+// jsonTimeFormat is the layout used for the "time" field of a JSON log line.
+// It matches the millisecond precision of the DefaultHandler's timestamps.
+const jsonTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// The keys used for the built-in fields of each JSON log line.
+const (
+	jsonTimeKey      = "time"
+	jsonLevelKey     = "level"
+	jsonSubSystemKey = "subsystem"
+	jsonSourceKey    = "source"
+	jsonMessageKey   = "msg"
+)
+
+// JSONHandler is a Handler that writes each log record as a single line
+// containing a JSON object. It accepts the same HandlerOption set as the
+// DefaultHandler and can be used along with NewSLogger to instantiate a
+// structured logger.
+//
+// The built-in fields are "time", "level" (in its TRC..CRT form),
+// "subsystem", "source" and "msg". All attributes follow these and groups are
+// rendered as nested objects.
+type JSONHandler struct {
+	opts *handlerOpts
+
+	level int64
+	tag   string
+
+	// preformatted is the JSON encoding of the attributes added via
+	// WithAttrs, including the opening of any groups that they were added
+	// under.
+	preformatted []byte
+
+	// preformattedSep is true if a separator is required before the next
+	// field that follows preformatted.
+	preformattedSep bool
+
+	// groups holds the names of all groups added with WithGroup.
+	groups []string
+
+	// openGroups is the number of entries in groups that have already
+	// been opened in preformatted.
+	openGroups int
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// A compile-time check to ensure that JSONHandler implements Handler.
+var _ Handler = (*JSONHandler)(nil)
+
+// NewJSONHandler creates a new Handler that writes JSON log lines to w. It can
+// be used along with NewSLogger to instantiate a structured logger.
+func NewJSONHandler(w io.Writer, options ...HandlerOption) *JSONHandler {
+	opts := defaultHandlerOpts()
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &JSONHandler{
+		w:               w,
+		level:           int64(levelInfo),
+		opts:            opts,
+		preformattedSep: true,
+		mu:              &sync.Mutex{},
+	}
+}
+
+// Level returns the current logging level of the Handler.
+//
+// NOTE: This is part of the Handler interface.
+func (j *JSONHandler) Level() btclog.Level {
+	return fromSlogLevel(slog.Level(atomic.LoadInt64(&j.level)))
+}
+
+// SetLevel changes the logging level of the Handler to the passed
+// level.
+//
+// NOTE: This is part of the Handler interface.
+func (j *JSONHandler) SetLevel(level btclog.Level) {
+	atomic.StoreInt64(&j.level, int64(toSlogLevel(level)))
+}
+
+// Enabled reports whether the handler handles records at the given level.
+// Records below the handler's level are still handled if the context has a
+// lower level associated with it via WithCtxLevel.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (j *JSONHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	handlerLevel := slog.Level(atomic.LoadInt64(&j.level))
+	if handlerLevel <= level {
+		return true
+	}
+
+	return ctxLevelEnabled(ctx, handlerLevel, level)
+}
+
+// Handle handles the Record. It will only be called if Enabled returns true.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (j *JSONHandler) Handle(ctx context.Context, r slog.Record) error {
+	r = extractCtxAttrs(ctx, r, j.opts.ctxExtractors)
+
+	buf := newBuffer()
+	defer buf.free()
+
+	enc := &jsonEncoder{buf: buf}
+	buf.writeByte('{')
+
+	// Timestamp.
+	if j.opts.withTimestamp {
+		// First check if the options provided specified a different
+		// time source to use. Otherwise, use the provided record time.
+		t := r.Time
+		if j.opts.timeSource != nil {
+			t = j.opts.timeSource()
+		}
+		if !t.IsZero() {
+			enc.writeKey(jsonTimeKey)
+			appendJSONString(buf, t.Format(jsonTimeFormat))
+		}
+	}
+
+	// Level.
+	enc.writeKey(jsonLevelKey)
+	appendJSONString(buf, fromSlogLevel(r.Level).String())
+
+	// Sub-system tag.
+	if j.tag != "" {
+		enc.writeKey(jsonSubSystemKey)
+		appendJSONString(buf, j.tag)
+	}
+
+	// The call-site.
+	if j.opts.flag&(Lshortfile|Llongfile) != 0 {
+		file, line := callsite(
+			j.opts.flag, j.opts.callSiteSkipDepth, r.PC,
+		)
+		enc.writeKey(jsonSourceKey)
+		appendJSONString(buf, file+":"+strconv.Itoa(line))
+	}
+
+	// The log message itself.
+	enc.writeKey(jsonMessageKey)
+	appendJSONString(buf, r.Message)
+
+	// Append logger fields.
+	buf.writeBytes(j.preformatted)
+	enc.sep = j.preformattedSep
+
+	// Append slog attributes. Any groups that have not been opened yet
+	// are only opened if there are attributes to put in them.
+	openGroups := j.openGroups
+	if r.NumAttrs() > 0 {
+		for _, g := range j.groups[j.openGroups:] {
+			enc.openGroup(g)
+			openGroups++
+		}
+
+		r.Attrs(func(a slog.Attr) bool {
+			enc.appendAttr(a)
+			return true
+		})
+	}
+
+	for i := 0; i < openGroups; i++ {
+		buf.writeByte('}')
+	}
+	buf.writeString("}\n")
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err := j.w.Write(*buf)
+
+	return err
+}
+
+// WithAttrs returns a new Handler with the given attributes added.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (j *JSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	sl := j.with(j.tag)
+	if len(attrs) == 0 {
+		return sl
+	}
+
+	pre := buffer(append([]byte(nil), j.preformatted...))
+	enc := &jsonEncoder{buf: &pre, sep: j.preformattedSep}
+
+	// Open any groups that were added since the last call to WithAttrs
+	// so that these attributes end up within them.
+	for _, g := range j.groups[j.openGroups:] {
+		enc.openGroup(g)
+	}
+	sl.openGroups = len(j.groups)
+
+	for _, attr := range attrs {
+		enc.appendAttr(attr)
+	}
+	sl.preformatted = pre
+	sl.preformattedSep = enc.sep
+
+	return sl
+}
+
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups. Any attributes added after this call will be
+// nested within a JSON object with the group name as key.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (j *JSONHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return j
+	}
+
+	sl := j.with(j.tag)
+	sl.groups = append(
+		make([]string, 0, len(j.groups)+1), j.groups...,
+	)
+	sl.groups = append(sl.groups, name)
+
+	return sl
+}
+
+// SubSystem returns a copy of the given handler but with the new tag. All
+// attributes added with WithAttrs and all groups added with WithGroup are kept.
+//
+// NOTE: This is part of the Handler interface.
+func (j *JSONHandler) SubSystem(tag string) Handler {
+	return j.with(tag)
+}
+
+// with returns a copy of the handler with the given tag.
+func (j *JSONHandler) with(tag string) *JSONHandler {
+	j.mu.Lock()
+	sl := *j
+	j.mu.Unlock()
+
+	sl.mu = &sync.Mutex{}
+	sl.tag = tag
+
+	return &sl
+}
+
+// jsonEncoder writes the fields of a JSON object to a buffer while keeping
+// track of where separators are required.
+type jsonEncoder struct {
+	buf *buffer
+
+	// sep is true if a ',' must be written before the next field.
+	sep bool
+}
+
+// writeKey writes the given key and the following ':' to the buffer, preceded
+// by a separator if required.
+func (e *jsonEncoder) writeKey(key string) {
+	if e.sep {
+		e.buf.writeByte(',')
+	}
+	appendJSONString(e.buf, key)
+	e.buf.writeByte(':')
+	e.sep = true
+}
+
+// openGroup starts a nested JSON object with the given name as its key.
+func (e *jsonEncoder) openGroup(name string) {
+	e.writeKey(name)
+	e.buf.writeByte('{')
+	e.sep = false
+}
+
+// appendAttr writes the given slog.Attr to the buffer as a JSON field.
+func (e *jsonEncoder) appendAttr(a slog.Attr) {
+	// Resolve the Attr's value before doing anything else.
+	a.Value = a.Value.Resolve()
+
+	// Ignore empty Attrs.
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+
+		// Ignore empty groups.
+		if len(attrs) == 0 {
+			return
+		}
+
+		// Inline the attributes of groups with empty keys.
+		if a.Key == "" {
+			for _, ga := range attrs {
+				e.appendAttr(ga)
+			}
+			return
+		}
+
+		e.openGroup(a.Key)
+		for _, ga := range attrs {
+			e.appendAttr(ga)
+		}
+		e.buf.writeByte('}')
+		e.sep = true
+
+		return
+	}
+
+	e.writeKey(a.Key)
+	appendJSONValue(e.buf, a.Value)
+}
+
+// appendJSONValue writes the given slog.Value to the buffer as a JSON value.
+func appendJSONValue(buf *buffer, v slog.Value) {
+	defer func() {
+		// Recovery in case of nil pointer dereferences.
+		if r := recover(); r != nil {
+			// Catch any panics that are most likely due to nil
+			// pointers.
+			appendJSONString(buf, fmt.Sprintf("!PANIC: %v", r))
+		}
+	}()
+
+	switch v.Kind() {
+	case slog.KindString:
+		appendJSONString(buf, v.String())
+	case slog.KindInt64:
+		*buf = strconv.AppendInt(*buf, v.Int64(), 10)
+	case slog.KindUint64:
+		*buf = strconv.AppendUint(*buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		// JSON has no representation of NaN or infinity, so these are
+		// written as strings instead.
+		f := v.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			s := strconv.FormatFloat(f, 'g', -1, 64)
+			appendJSONString(buf, s)
+			return
+		}
+		*buf = strconv.AppendFloat(*buf, f, 'g', -1, 64)
+	case slog.KindBool:
+		*buf = strconv.AppendBool(*buf, v.Bool())
+	case slog.KindDuration:
+		*buf = strconv.AppendInt(*buf, int64(v.Duration()), 10)
+	case slog.KindTime:
+		appendJSONString(buf, v.Time().Format(time.RFC3339Nano))
+	default:
+		appendJSONAny(buf, v.Any())
+	}
+}
+
+// appendJSONAny writes an arbitrary value to the buffer. Errors that don't
+// implement json.Marshaler are written using their Error string, everything
+// else is encoded using the encoding/json package.
+func appendJSONAny(buf *buffer, a any) {
+	if err, ok := a.(error); ok {
+		if _, ok := a.(json.Marshaler); !ok {
+			appendJSONString(buf, err.Error())
+			return
+		}
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		appendJSONString(buf, fmt.Sprintf("!ERROR: %v", err))
+		return
+	}
+	buf.writeBytes(b)
+}
+
+// hexDigits is used to write the escaped form of control characters.
+const hexDigits = "0123456789abcdef"
+
+// Adapted from log/slog/json_handler.go.
+//
+// appendJSONString writes the given string to the buffer as a quoted and
+// escaped JSON string.
+func appendJSONString(buf *buffer, s string) {
+	buf.writeByte('"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if safeSet[b] {
+				i++
+				continue
+			}
+			buf.writeString(s[start:i])
+			switch b {
+			case '\\', '"':
+				buf.writeByte('\\')
+				buf.writeByte(b)
+			case '\n':
+				buf.writeString(`\n`)
+			case '\r':
+				buf.writeString(`\r`)
+			case '\t':
+				buf.writeString(`\t`)
+			default:
+				// This encodes bytes < 0x20 except for \t, \n
+				// and \r.
+				buf.writeString(`\u00`)
+				buf.writeByte(hexDigits[b>>4])
+				buf.writeByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf.writeString(s[start:i])
+			buf.writeString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+
+		// U+2028 is LINE SEPARATOR and U+2029 is PARAGRAPH SEPARATOR.
+		// They are both technically valid characters in JSON strings,
+		// but don't work in JSONP, so they are escaped as well.
+		if c == '\u2028' || c == '\u2029' {
+			buf.writeString(s[start:i])
+			buf.writeString(`\u202`)
+			buf.writeByte(hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.writeString(s[start:])
+
+	buf.writeByte('"')
+}
@@ -0,0 +1,140 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// TestJSONHandler tests that the JSONHandler's output looks as expected.
+func TestJSONHandler(t *testing.T) {
+	t.Parallel()
+
+	timeSource := func() time.Time {
+		return time.Date(2024, 10, 3, 13, 34, 17, 123e6, time.UTC)
+	}
+
+	tests := []struct {
+		name               string
+		handlerConstructor func(w io.Writer) Handler
+		level              btclog.Level
+		logFunc            func(log Logger)
+		expectedLog        string
+	}{
+		{
+			name: "Basic calls and levels",
+			handlerConstructor: func(w io.Writer) Handler {
+				return NewJSONHandler(
+					w, WithTimeSource(timeSource),
+				)
+			},
+			level: LevelDebug,
+			logFunc: func(log Logger) {
+				log.Info("Test Basic Log")
+				log.Debugf("Test basic log with %s", "format")
+				log.Trace("Log should not appear due to level")
+			},
+			expectedLog: `{"time":"2024-10-03T13:34:17.123Z","level":"INF","msg":"Test Basic Log"}
+{"time":"2024-10-03T13:34:17.123Z","level":"DBG","msg":"Test basic log with format"}
+`,
+		},
+		{
+			name: "Sub-system tag",
+			handlerConstructor: func(w io.Writer) Handler {
+				h := NewJSONHandler(w, WithNoTimestamp())
+				return h.SubSystem("SUBS")
+			},
+			level: LevelInfo,
+			logFunc: func(log Logger) {
+				log.Info("Test Basic Log")
+			},
+			expectedLog: `{"level":"INF","subsystem":"SUBS","msg":"Test Basic Log"}
+`,
+		},
+		{
+			name: "Structured Logs",
+			handlerConstructor: func(w io.Writer) Handler {
+				return NewJSONHandler(w, WithNoTimestamp())
+			},
+			level: LevelInfo,
+			logFunc: func(log Logger) {
+				ctx := context.Background()
+				log.InfoS(ctx, "No attributes")
+				log.InfoS(ctx, "Typed attributes", "str", "a \"b\"",
+					"int", -5, "uint", uint64(5), "float", 1.5,
+					"bool", true, "dur", time.Second)
+				log.InfoS(ctx, "Group", slog.Group("g",
+					slog.Int("a", 1), slog.Group("h",
+						slog.Int("b", 2))))
+
+				ctx = WithCtx(ctx, "request_id", 5)
+				log.WarnS(ctx, "Context", errors.New("oh no"),
+					"key", "value")
+			},
+			expectedLog: `{"level":"INF","msg":"No attributes"}
+{"level":"INF","msg":"Typed attributes","str":"a \"b\"","int":-5,"uint":5,"float":1.5,"bool":true,"dur":1000000000}
+{"level":"INF","msg":"Group","g":{"a":1,"h":{"b":2}}}
+{"level":"WRN","msg":"Context","request_id":5,"err":"oh no","key":"value"}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := test.handlerConstructor(&buf)
+			handler.SetLevel(test.level)
+
+			if handler.Level() != test.level {
+				t.Fatalf("Incorrect level. Expected %s, "+
+					"got %s", test.level, handler.Level())
+			}
+
+			test.logFunc(NewSLogger(handler))
+
+			if string(buf.Bytes()) != test.expectedLog {
+				t.Fatalf("Log result mismatch. Expected "+
+					"\n\"%s\", got \n\"%s\"",
+					test.expectedLog, buf.Bytes())
+			}
+		})
+	}
+}
+
+// TestJSONHandlerWithAttrs tests that attributes and groups added to the
+// JSONHandler through slog's With and WithGroup produce valid JSON objects
+// with the expected nesting.
+func TestJSONHandlerWithAttrs(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	h := NewJSONHandler(&buf, WithNoTimestamp())
+
+	logger := slog.New(h).With("a", 1).WithGroup("g").With("b", 2).
+		WithGroup("h")
+
+	logger.Info("With attrs", "c", 3)
+	logger.Info("Without attrs")
+
+	expected := `{"level":"INF","msg":"With attrs","a":1,"g":{"b":2,"h":{"c":3}}}
+{"level":"INF","msg":"Without attrs","a":1,"g":{"b":2}}
+`
+	if buf.String() != expected {
+		t.Fatalf("Log result mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expected, buf.String())
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	for _, line := range lines {
+		if !json.Valid(line) {
+			t.Fatalf("Invalid JSON line: %s", line)
+		}
+	}
+}
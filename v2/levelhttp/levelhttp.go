@@ -0,0 +1,218 @@
+// Package levelhttp provides an http.Handler that can be used to inspect and
+// change the log levels of the sub-systems in a btclog.SubSystemRegistry at
+// runtime.
+//
+// The handler serves the following requests relative to the path it is
+// mounted on:
+//
+//	GET  /       lists all sub-systems and their levels.
+//	PUT  /       applies a level spec, e.g. {"spec": "info,PEER=debug"}.
+//	GET  /{tag}  returns the level of a single sub-system.
+//	PUT  /{tag}  sets the level of a single sub-system, e.g.
+//	             {"level": "debug"}.
+//
+// All responses are JSON encoded. Errors are returned as {"error": "..."}
+// along with an appropriate status code.
+package levelhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/btcsuite/btclog/v2"
+)
+
+// maxBodySize is the maximum size of a request body that will be read.
+const maxBodySize = 1 << 16
+
+// Levels maps sub-system tags to their current level.
+type Levels map[string]string
+
+// SubSystemLevel is the level of a single sub-system.
+type SubSystemLevel struct {
+	// SubSystem is the tag of the sub-system.
+	SubSystem string `json:"subsystem"`
+
+	// Level is the current level of the sub-system.
+	Level string `json:"level"`
+}
+
+// SetLevelRequest is the body of a PUT request for a single sub-system.
+type SetLevelRequest struct {
+	// Level is the new level of the sub-system, in any form that is
+	// understood by btclog.LevelFromString.
+	Level string `json:"level"`
+}
+
+// SetSpecRequest is the body of a PUT request for all sub-systems.
+type SetSpecRequest struct {
+	// Spec is a level spec as understood by btclog.ParseLevelSpec.
+	Spec string `json:"spec"`
+}
+
+// errorResponse is the body of all error responses.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler is an http.Handler that serves the levels of the sub-systems in a
+// registry.
+type Handler struct {
+	registry *btclog.SubSystemRegistry
+}
+
+// A compile-time check to ensure that Handler implements http.Handler.
+var _ http.Handler = (*Handler)(nil)
+
+// NewHandler creates a new Handler for the given registry. The returned
+// Handler can be mounted on any path of an existing mux using http.StripPrefix,
+// for example:
+//
+//	mux.Handle("/debug/loglevel/", http.StripPrefix("/debug/loglevel",
+//		levelhttp.NewHandler(registry)))
+func NewHandler(registry *btclog.SubSystemRegistry) *Handler {
+	return &Handler{
+		registry: registry,
+	}
+}
+
+// ServeHTTP routes the request to the matching endpoint.
+//
+// NOTE: This is part of the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tag := strings.Trim(r.URL.Path, "/")
+
+	switch {
+	case tag == "" && r.Method == http.MethodGet:
+		h.getLevels(w)
+
+	case tag == "" && r.Method == http.MethodPut:
+		h.putSpec(w, r)
+
+	case strings.Contains(tag, "/"):
+		writeError(w, http.StatusNotFound, "unknown path")
+
+	case r.Method == http.MethodGet:
+		h.getLevel(w, tag)
+
+	case r.Method == http.MethodPut:
+		h.putLevel(w, r, tag)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(
+			w, http.StatusMethodNotAllowed, "method not allowed",
+		)
+	}
+}
+
+// getLevels writes the levels of all registered sub-systems.
+func (h *Handler) getLevels(w http.ResponseWriter) {
+	levels := make(Levels)
+	for _, tag := range h.registry.SubSystems() {
+		if level, ok := h.registry.Level(tag); ok {
+			levels[tag] = level.String()
+		}
+	}
+
+	writeJSON(w, http.StatusOK, levels)
+}
+
+// getLevel writes the level of a single sub-system.
+func (h *Handler) getLevel(w http.ResponseWriter, tag string) {
+	level, ok := h.registry.Level(tag)
+	if !ok {
+		writeError(
+			w, http.StatusNotFound, "unknown sub-system: "+tag,
+		)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SubSystemLevel{
+		SubSystem: tag,
+		Level:     level.String(),
+	})
+}
+
+// putSpec applies the level spec in the request body to all sub-systems and
+// writes their new levels.
+func (h *Handler) putSpec(w http.ResponseWriter, r *http.Request) {
+	var req SetSpecRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.registry.ApplyLevelSpec(req.Spec); err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	h.getLevels(w)
+}
+
+// putLevel sets the level in the request body on a single sub-system and
+// writes its new level.
+func (h *Handler) putLevel(w http.ResponseWriter, r *http.Request,
+	tag string) {
+
+	var req SetLevelRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	level, ok := btclog.LevelFromString(req.Level)
+	if !ok {
+		writeError(w, http.StatusBadRequest,
+			"invalid log level: "+req.Level)
+		return
+	}
+
+	if err := h.registry.SetLevel(tag, level); err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	h.getLevel(w, tag)
+}
+
+// readJSON decodes the JSON request body into v. If this fails, an error
+// response is written and false is returned.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest,
+			"invalid request: "+err.Error())
+		return false
+	}
+
+	return true
+}
+
+// writeRegistryError writes an error returned by the registry with a status
+// code that matches its cause.
+func writeRegistryError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, btclog.ErrUnknownSubSystem) {
+		status = http.StatusNotFound
+	}
+
+	writeError(w, status, err.Error())
+}
+
+// writeError writes an error response with the given status code.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
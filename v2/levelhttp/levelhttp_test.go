@@ -0,0 +1,166 @@
+package levelhttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btclog/v2"
+)
+
+// TestHandler tests the endpoints of the Handler when mounted on a sub-path of
+// a mux.
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	registry := btclog.NewSubSystemRegistry(
+		btclog.NewDefaultHandler(io.Discard),
+	)
+	peer := registry.Logger("PEER")
+	registry.Logger("SRVR")
+
+	mux := http.NewServeMux()
+	mux.Handle("/loglevel/", http.StripPrefix(
+		"/loglevel", NewHandler(registry),
+	))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(
+			method, server.URL+"/loglevel"+path,
+			strings.NewReader(body),
+		)
+		if err != nil {
+			t.Fatalf("Unable to create request: %v", err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Unable to read response: %v", err)
+		}
+
+		return resp.StatusCode, strings.TrimSpace(string(b))
+	}
+
+	tests := []struct {
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			method:         http.MethodGet,
+			path:           "/",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"PEER":"INF","SRVR":"INF"}`,
+		},
+		{
+			method:         http.MethodPut,
+			path:           "/PEER",
+			body:           `{"level":"trace"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"subsystem":"PEER","level":"TRC"}`,
+		},
+		{
+			method:         http.MethodGet,
+			path:           "/PEER",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"subsystem":"PEER","level":"TRC"}`,
+		},
+		{
+			method:         http.MethodPut,
+			path:           "/",
+			body:           `{"spec":"warn,SRVR=debug"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"PEER":"WRN","SRVR":"DBG"}`,
+		},
+		{
+			method:         http.MethodGet,
+			path:           "/RPCS",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"unknown sub-system: RPCS"}`,
+		},
+		{
+			method:         http.MethodPut,
+			path:           "/PEER",
+			body:           `{"level":"verbose"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid log level: verbose"}`,
+		},
+		{
+			method:         http.MethodPut,
+			path:           "/",
+			body:           `{"spec":"info,RPCS=debug"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"unknown sub-system: RPCS"}`,
+		},
+		{
+			method:         http.MethodDelete,
+			path:           "/PEER",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   `{"error":"method not allowed"}`,
+		},
+	}
+
+	for _, test := range tests {
+		status, body := do(test.method, test.path, test.body)
+		if status != test.expectedStatus {
+			t.Fatalf("%s %s: expected status %d, got %d (%s)",
+				test.method, test.path, test.expectedStatus,
+				status, body)
+		}
+		if body != test.expectedBody {
+			t.Fatalf("%s %s: expected body %s, got %s",
+				test.method, test.path, test.expectedBody,
+				body)
+		}
+	}
+
+	// The failed spec must not have changed any levels.
+	if peer.Level() != btclog.LevelWarn {
+		t.Fatalf("Expected PEER level %s, got %s", btclog.LevelWarn,
+			peer.Level())
+	}
+}
+
+// TestHandlerRecorder tests that the Handler can be exercised without a
+// server using an httptest.ResponseRecorder.
+func TestHandlerRecorder(t *testing.T) {
+	t.Parallel()
+
+	registry := btclog.NewSubSystemRegistry(
+		btclog.NewDefaultHandler(io.Discard),
+	)
+	registry.Logger("PEER")
+
+	rec := httptest.NewRecorder()
+	NewHandler(registry).ServeHTTP(
+		rec, httptest.NewRequest(http.MethodGet, "/", nil),
+	)
+
+	var levels Levels
+	if err := json.NewDecoder(rec.Body).Decode(&levels); err != nil {
+		t.Fatalf("Unable to decode response: %v", err)
+	}
+	if !reflect.DeepEqual(levels, Levels{"PEER": "INF"}) {
+		t.Fatalf("Unexpected levels: %v", levels)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected content type: %s",
+			rec.Header().Get("Content-Type"))
+	}
+}
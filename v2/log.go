@@ -6,6 +6,8 @@ import (
 	"github.com/btcsuite/btclog"
 	"io"
 	"log/slog"
+	"runtime"
+	"time"
 )
 
 // Disabled is a Logger that will never output anything.
@@ -30,14 +32,12 @@ type Handler interface {
 // sLogger is an implementation of Logger backed by a structured sLogger.
 type sLogger struct {
 	Handler
-	logger *slog.Logger
 }
 
 // NewSLogger constructs a new structured logger from the given Handler.
 func NewSLogger(handler Handler) Logger {
 	return &sLogger{
 		Handler: handler,
-		logger:  slog.New(handler),
 	}
 }
 
@@ -204,25 +204,60 @@ func (l *sLogger) CriticalS(ctx context.Context, msg string, err error,
 
 // toSlogf is a helper method that converts an unstructured log call that
 // contains a format string and parameters for the string into the appropriate
-// form expected by the structured logger.
+// form expected by the structured logger. The message is only formatted if the
+// Handler is enabled for the given level.
 func (l *sLogger) toSlogf(level slog.Level, format string, params ...any) {
-	l.logger.Log(context.Background(), level,
-		fmt.Sprintf(format, params...))
+	ctx := context.Background()
+	if !l.Handler.Enabled(ctx, level) {
+		return
+	}
+
+	r := slog.NewRecord(
+		time.Now(), level, fmt.Sprintf(format, params...), callerPC(),
+	)
+	_ = l.Handler.Handle(ctx, r)
 }
 
 // toSlog is a helper method that converts an unstructured log call that
 // contains a number of parameters into the appropriate form expected by the
-// structured logger.
+// structured logger. The message is only formatted if the Handler is enabled
+// for the given level.
 func (l *sLogger) toSlog(level slog.Level, v ...any) {
-	l.logger.Log(context.Background(), level, fmt.Sprint(v...))
+	ctx := context.Background()
+	if !l.Handler.Enabled(ctx, level) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), level, fmt.Sprint(v...), callerPC())
+	_ = l.Handler.Handle(ctx, r)
 }
 
 // toSlogS is a helper method that can be used by all the structured log calls
-// to access the underlying logger.
+// to access the underlying logger. The attributes are only merged with those of
+// the context if the Handler is enabled for the given level.
 func (l *sLogger) toSlogS(ctx context.Context, level slog.Level, msg string,
 	attrs ...any) {
 
-	l.logger.Log(ctx, level, msg, mergeAttrs(ctx, attrs)...)
+	if !l.Handler.Enabled(ctx, level) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), level, msg, callerPC())
+	r.Add(mergeAttrs(ctx, attrs)...)
+	_ = l.Handler.Handle(ctx, r)
+}
+
+// callerPC returns the program counter of the caller of the Logger method that
+// called one of the toSlog helpers. It is set as the PC of each record so that
+// Handlers can log the call-site without depending on the depth of the call
+// stack, which changes whenever a Handler is wrapped by another.
+func callerPC() uintptr {
+	// Skip runtime.Callers, callerPC, the toSlog helper and the Logger
+	// method.
+	var pcs [1]uintptr
+	runtime.Callers(4, pcs[:])
+
+	return pcs[0]
 }
 
 var _ Logger = (*sLogger)(nil)
@@ -0,0 +1,113 @@
+package btclog
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+// disabledLogCalls are log calls at levels below LevelInfo, using arguments
+// that don't allocate when converted to an interface value.
+//
+// NOTE: the calls are made on the concrete sLogger. When called through the
+// Logger interface, the compiler can't prove that the variadic arguments don't
+// escape, so it allocates their backing array on the heap before the logger is
+// even reached.
+var disabledLogCalls = []struct {
+	name string
+	fn   func(log *sLogger, ctx context.Context, ptr *int)
+}{
+	{
+		name: "Tracef",
+		fn: func(log *sLogger, _ context.Context, ptr *int) {
+			log.Tracef("Trace %v %s", ptr, "string")
+		},
+	},
+	{
+		name: "Debugf",
+		fn: func(log *sLogger, _ context.Context, ptr *int) {
+			log.Debugf("Debug %v %s", ptr, "string")
+		},
+	},
+	{
+		name: "Trace",
+		fn: func(log *sLogger, _ context.Context, ptr *int) {
+			log.Trace("Trace", ptr, "string")
+		},
+	},
+	{
+		name: "Debug",
+		fn: func(log *sLogger, _ context.Context, ptr *int) {
+			log.Debug("Debug", ptr, "string")
+		},
+	},
+	{
+		name: "TraceS",
+		fn: func(log *sLogger, ctx context.Context, ptr *int) {
+			log.TraceS(ctx, "Trace", "ptr", ptr, "key", "value")
+		},
+	},
+	{
+		name: "DebugS",
+		fn: func(log *sLogger, ctx context.Context, ptr *int) {
+			log.DebugS(ctx, "Debug", "ptr", ptr, "key", "value")
+		},
+	},
+}
+
+// TestDisabledLevelsNoAllocs tests that log calls for disabled levels don't
+// allocate, meaning that no formatting is done for them.
+func TestDisabledLevelsNoAllocs(t *testing.T) {
+	log := NewSLogger(NewDefaultHandler(io.Discard)).(*sLogger)
+	log.SetLevel(LevelInfo)
+
+	ctx := WithCtx(context.Background(), "key", "value")
+	ptr := new(int)
+
+	for _, call := range disabledLogCalls {
+		allocs := testing.AllocsPerRun(100, func() {
+			call.fn(log, ctx, ptr)
+		})
+		if allocs != 0 {
+			t.Fatalf("%s: expected 0 allocations, got %v",
+				call.name, allocs)
+		}
+	}
+}
+
+// BenchmarkDisabledLevels benchmarks log calls for levels that are disabled.
+func BenchmarkDisabledLevels(b *testing.B) {
+	log := NewSLogger(NewDefaultHandler(io.Discard)).(*sLogger)
+	log.SetLevel(LevelInfo)
+
+	ctx := WithCtx(context.Background(), "key", "value")
+	ptr := new(int)
+
+	for _, call := range disabledLogCalls {
+		b.Run(call.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				call.fn(log, ctx, ptr)
+			}
+		})
+	}
+}
+
+// BenchmarkEnabledLevels benchmarks log calls for levels that are enabled to
+// serve as a baseline for BenchmarkDisabledLevels.
+func BenchmarkEnabledLevels(b *testing.B) {
+	log := NewSLogger(NewDefaultHandler(io.Discard)).(*sLogger)
+	log.SetLevel(LevelTrace)
+
+	ctx := WithCtx(context.Background(), "key", "value")
+	ptr := new(int)
+
+	for _, call := range disabledLogCalls {
+		b.Run(call.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				call.fn(log, ctx, ptr)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package logreader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btclog/v2"
+)
+
+// ErrNotJSON is returned by ParseJSONLine if the line is not a JSON object
+// with at least the level and message fields of a JSONHandler record.
+var ErrNotJSON = errors.New("line is not a JSON log record")
+
+// The keys of the built-in fields written by the JSONHandler.
+const (
+	jsonTimeKey      = "time"
+	jsonLevelKey     = "level"
+	jsonSubSystemKey = "subsystem"
+	jsonSourceKey    = "source"
+	jsonMessageKey   = "msg"
+)
+
+// ParseJSONLine parses a single log line as written by the JSONHandler. The
+// time of the record is converted to the given location. Attributes that
+// were logged in a group are flattened into dotted keys, in the same way as
+// the DefaultHandler renders them. String values are unquoted while all other
+// values are kept in their JSON form. Since the line may as well be part of a
+// multi-line text record, such as a pretty-printed request body, only objects
+// with both a level and a message field are accepted as records.
+func ParseJSONLine(line string, loc *time.Location) (*Record, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return nil, ErrNotJSON
+	}
+
+	rec := &Record{
+		Level: btclog.LevelInfo,
+	}
+	seen := make(map[string]bool)
+	err := parseJSONObject([]byte(line), "", rec, seen, loc)
+	if err != nil {
+		return nil, err
+	}
+	if !seen[jsonLevelKey] || !seen[jsonMessageKey] {
+		return nil, ErrNotJSON
+	}
+
+	return rec, nil
+}
+
+// parseJSONObject parses the JSON object in data and adds its fields to rec.
+// The fields of nested objects are added with their keys prefixed by that of
+// the object. The keys of the built-in fields that were set are added to seen,
+// and only the first field with such a key sets the built-in field.
+func parseJSONObject(data []byte, prefix string, rec *Record,
+	seen map[string]bool, loc *time.Location) error {
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return ErrNotJSON
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		if raw[0] == '{' {
+			err := parseJSONObject(
+				raw, prefix+key+".", rec, seen, loc,
+			)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		value := string(raw)
+		if raw[0] == '"' {
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return err
+			}
+
+			// The built-in fields are written first, so any later
+			// field with the same key is a user attribute that
+			// must not replace the built-in field.
+			if prefix == "" && !seen[key] &&
+				setJSONField(rec, key, value, loc) {
+
+				seen[key] = true
+				continue
+			}
+		}
+
+		rec.Attrs = append(rec.Attrs, Attr{
+			Key:   prefix + key,
+			Value: value,
+		})
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setJSONField sets the built-in field of the record with the given key and
+// returns true. False is returned if the key or value don't match any of the
+// built-in fields.
+func setJSONField(rec *Record, key, value string, loc *time.Location) bool {
+	switch key {
+	case jsonTimeKey:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return false
+		}
+		rec.Time = t.In(loc)
+
+	case jsonLevelKey:
+		level, ok := btclog.LevelFromString(value)
+		if !ok {
+			return false
+		}
+		rec.Level = level
+
+	case jsonSubSystemKey:
+		rec.Tag = value
+
+	case jsonSourceKey:
+		file, line, ok := parseCallSite(value)
+		if !ok {
+			return false
+		}
+		rec.File, rec.Line = file, line
+
+	case jsonMessageKey:
+		rec.Message = value
+
+	default:
+		return false
+	}
+
+	return true
+}
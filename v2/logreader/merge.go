@@ -0,0 +1,185 @@
+package logreader
+
+import (
+	"container/heap"
+	"io"
+	"time"
+)
+
+// Source is a single input of a Merger.
+type Source struct {
+	// Label identifies the source, for example the name of the node that
+	// wrote the log.
+	Label string
+
+	// Reader is the log that is read, in either the text or JSON format.
+	Reader io.Reader
+}
+
+// MergedRecord is a record that was read by a Merger, along with the source it
+// was read from.
+type MergedRecord struct {
+	*Record
+
+	// Label is the label of the source that the record was read from.
+	Label string
+
+	// Source is the index of the source that the record was read from.
+	Source int
+}
+
+// Merger interleaves the records of several logs into a single timeline
+// ordered by the records' timestamps. Each log is assumed to be mostly
+// ordered already, which is the case for any log written by a single process.
+//
+// Since the timestamps only have millisecond precision, many records have the
+// same timestamp. The merge is stable and deterministic:
+//
+//   - Records with the same timestamp are ordered by the index of their source
+//     and then by their position within that source.
+//   - The records of a single source are never reordered. A record with a
+//     timestamp that is earlier than that of its predecessor, or that has no
+//     timestamp at all, is sorted as if it had the predecessor's timestamp.
+type Merger struct {
+	sources  []Source
+	scanners []*Scanner
+	heap     mergeHeap
+	started  bool
+
+	rec *MergedRecord
+	err error
+}
+
+// NewMerger creates a new Merger that reads the records of all given sources.
+// The options are applied to each source's Scanner.
+func NewMerger(sources []Source, options ...Option) *Merger {
+	m := &Merger{
+		sources:  sources,
+		scanners: make([]*Scanner, len(sources)),
+	}
+	for i, src := range sources {
+		m.scanners[i] = NewScanner(src.Reader, options...)
+	}
+
+	return m
+}
+
+// Next advances the Merger to the next record in the merged timeline, which is
+// then available through Record. It returns false when all sources have been
+// read completely or when an error occurred while reading any of them.
+func (m *Merger) Next() bool {
+	m.rec = nil
+	if m.err != nil {
+		return false
+	}
+
+	if !m.started {
+		m.started = true
+		for i := range m.scanners {
+			if !m.push(i, time.Time{}) {
+				return false
+			}
+		}
+	}
+
+	if m.heap.Len() == 0 {
+		return false
+	}
+
+	item := heap.Pop(&m.heap).(*mergeItem)
+	m.rec = item.rec
+	if !m.push(item.rec.Source, item.key) {
+		m.rec = nil
+		return false
+	}
+
+	return true
+}
+
+// Record returns the record that was read by the last call to Next.
+func (m *Merger) Record() *MergedRecord {
+	return m.rec
+}
+
+// Err returns the first error that was encountered while reading any of the
+// sources.
+func (m *Merger) Err() error {
+	return m.err
+}
+
+// push reads the next record of the given source and adds it to the heap. The
+// given key is that of the source's previous record. False is returned if an
+// error occurred.
+func (m *Merger) push(source int, prev time.Time) bool {
+	s := m.scanners[source]
+	if !s.Next() {
+		m.err = s.Err()
+		return m.err == nil
+	}
+
+	rec := s.Record()
+	key := rec.Time
+	if key.Before(prev) {
+		key = prev
+	}
+
+	heap.Push(&m.heap, &mergeItem{
+		rec: &MergedRecord{
+			Record: rec,
+			Label:  m.sources[source].Label,
+			Source: source,
+		},
+		key: key,
+	})
+
+	return true
+}
+
+// mergeItem is the next record of a single source of a Merger.
+type mergeItem struct {
+	rec *MergedRecord
+
+	// key is the timestamp that the record is sorted by.
+	key time.Time
+}
+
+// mergeHeap is a min-heap of the next record of each source. Since it holds at
+// most one record per source, the records of a single source stay in order.
+//
+// NOTE: This implements the heap.Interface interface.
+type mergeHeap []*mergeItem
+
+// Len returns the number of items in the heap.
+func (h mergeHeap) Len() int {
+	return len(h)
+}
+
+// Less returns true if item i is ordered before item j.
+func (h mergeHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if !a.key.Equal(b.key) {
+		return a.key.Before(b.key)
+	}
+
+	return a.rec.Source < b.rec.Source
+}
+
+// Swap swaps items i and j.
+func (h mergeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+// Push adds an item to the heap.
+func (h *mergeHeap) Push(x any) {
+	*h = append(*h, x.(*mergeItem))
+}
+
+// Pop removes the last item from the heap.
+func (h *mergeHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return item
+}
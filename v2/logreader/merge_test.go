@@ -0,0 +1,123 @@
+package logreader
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog/v2"
+)
+
+// TestParseJSONLine tests that a line written by the JSONHandler is parsed
+// into the expected record.
+func TestParseJSONLine(t *testing.T) {
+	t.Parallel()
+
+	line := `{"time":"2024-10-03T15:34:17.123+02:00","level":"WRN",` +
+		`"subsystem":"PEER","source":"peer.go:42","msg":"Slow peer",` +
+		`"peer":"1.2.3.4","latency":1.5,"req":{"id":7,"ok":true}}`
+
+	rec, err := ParseJSONLine(line, time.UTC)
+	if err != nil {
+		t.Fatalf("Unable to parse: %v", err)
+	}
+
+	expected := &Record{
+		Time:    time.Date(2024, 10, 3, 13, 34, 17, 123e6, time.UTC),
+		Level:   btclog.LevelWarn,
+		Tag:     "PEER",
+		File:    "peer.go",
+		Line:    42,
+		Message: "Slow peer",
+		Attrs: []Attr{
+			{"peer", "1.2.3.4"},
+			{"latency", "1.5"},
+			{"req.id", "7"},
+			{"req.ok", "true"},
+		},
+	}
+	if !reflect.DeepEqual(rec, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, rec)
+	}
+
+	// Lines that aren't JSON objects, or that lack the level or message
+	// field of a record, aren't records.
+	for _, line := range []string{
+		"[INF]: text", `{"amount":5}`, `{"level":"INF"}`,
+		`{"msg":"text"}`, `{"level":"bogus","msg":"text"}`,
+	} {
+		if _, err := ParseJSONLine(line, time.UTC); err != ErrNotJSON {
+			t.Fatalf("%s: expected ErrNotJSON, got %v", line, err)
+		}
+	}
+}
+
+// TestMerger tests that the records of several sources are merged into a
+// single, deterministically ordered timeline.
+func TestMerger(t *testing.T) {
+	t.Parallel()
+
+	alice := `2024-10-03 13:00:00.001 [INF] PEER: a1
+2024-10-03 13:00:00.003 [INF] PEER: a2
+continued
+[INF] PEER: a3 without timestamp
+2024-10-03 13:00:00.002 [INF] PEER: a4 out of order
+2024-10-03 13:00:00.005 [INF] PEER: a5
+`
+	bob := `{"time":"2024-10-03T13:00:00.001Z","level":"INF","msg":"b1"}
+{"time":"2024-10-03T13:00:00.003Z","level":"INF","msg":"b2",` +
+		`"msg":"x","time":"2030-01-01T00:00:00Z","level":"CRT"}
+{"time":"2024-10-03T13:00:00.004Z","level":"INF","msg":"b3"}
+`
+	carol := `2024-10-03 13:00:00.000 [INF] PEER: c1
+2024-10-03 13:00:00.003 [INF] PEER: c2
+`
+
+	m := NewMerger([]Source{
+		{Label: "alice", Reader: strings.NewReader(alice)},
+		{Label: "bob", Reader: strings.NewReader(bob)},
+		{Label: "carol", Reader: strings.NewReader(carol)},
+	}, WithLocation(time.UTC))
+
+	var (
+		got []string
+		b2  *MergedRecord
+	)
+	for m.Next() {
+		rec := m.Record()
+		got = append(got, fmt.Sprintf("%s:%s", rec.Label,
+			strings.Fields(rec.Message)[0]))
+
+		if rec.Message == "b2" {
+			b2 = rec
+		}
+	}
+	if err := m.Err(); err != nil {
+		t.Fatalf("Unable to merge: %v", err)
+	}
+
+	// Records with the same timestamp are ordered by source, while the
+	// records a3 and a4 keep their position after a2.
+	expected := []string{
+		"carol:c1", "alice:a1", "bob:b1", "alice:a2", "alice:a3",
+		"alice:a4", "bob:b2", "carol:c2", "bob:b3", "alice:a5",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+
+	// Attributes that collide with the built-in fields of a JSON record
+	// don't replace them.
+	expectedAttrs := []Attr{
+		{"msg", "x"},
+		{"time", "2030-01-01T00:00:00Z"},
+		{"level", "CRT"},
+	}
+	if b2 == nil || b2.Level != btclog.LevelInfo ||
+		!reflect.DeepEqual(b2.Attrs, expectedAttrs) {
+
+		t.Fatalf("Unexpected record b2: %+v", b2)
+	}
+}
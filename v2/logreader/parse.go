@@ -0,0 +1,213 @@
+package logreader
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btclog/v2"
+)
+
+// ErrNotHeader is returned by ParseLine if the line does not start with a log
+// header. Such lines are usually the continuation of a multi-line message.
+var ErrNotHeader = errors.New("line does not start with a log header")
+
+// ParseLine parses a single log line of the form
+//
+//	YYYY-MM-DD hh:mm:ss.sss [LVL] TAG file:line: msg k=v ...
+//
+// as written by the DefaultHandler and by the v1 Backend. The timestamp, tag
+// and call-site are optional. Timestamps are interpreted in the given
+// location. Terminal color escape sequences are ignored.
+//
+// Since the message itself is not quoted, any trailing words of the message
+// that look like key-value pairs are parsed as attributes.
+func ParseLine(line string, loc *time.Location) (*Record, error) {
+	rec, body, err := parseHeader(line, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	rec.Message, rec.Attrs = parseBody(body)
+
+	return rec, nil
+}
+
+// parseHeader parses the header of a log line and returns the partially
+// filled in record along with the unparsed body of the line.
+func parseHeader(line string, loc *time.Location) (*Record, string, error) {
+	line = stripANSI(strings.TrimRight(line, "\r\n"))
+
+	rec := &Record{}
+
+	// The optional timestamp.
+	rest := line
+	n := len(timestampFormat)
+	if len(rest) > n && rest[n] == ' ' {
+		t, err := time.ParseInLocation(timestampFormat, rest[:n], loc)
+		if err == nil {
+			rec.Time = t
+			rest = rest[n+1:]
+		}
+	}
+
+	// The level.
+	if len(rest) < 5 || rest[0] != '[' || rest[4] != ']' {
+		return nil, "", ErrNotHeader
+	}
+	level, ok := btclog.LevelFromString(rest[1:4])
+	if !ok {
+		return nil, "", ErrNotHeader
+	}
+	rec.Level = level
+	rest = rest[5:]
+
+	// The tag and call-site are terminated by the first ": ". A line that
+	// ends with the header has an empty message.
+	end := strings.Index(rest, ": ")
+	if end < 0 {
+		if !strings.HasSuffix(rest, ":") {
+			return nil, "", ErrNotHeader
+		}
+		end = len(rest) - 1
+	}
+
+	fields := strings.Fields(rest[:end])
+	if n := len(fields); n > 0 {
+		if file, line, ok := parseCallSite(fields[n-1]); ok {
+			rec.File, rec.Line = file, line
+			fields = fields[:n-1]
+		}
+	}
+	rec.Tag = strings.Join(fields, " ")
+
+	body := ""
+	if end+2 <= len(rest) {
+		body = rest[end+2:]
+	}
+
+	return rec, body, nil
+}
+
+// parseCallSite parses a call-site of the form file:line.
+func parseCallSite(s string) (string, int, bool) {
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 {
+		return "", 0, false
+	}
+
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil || line < 0 {
+		return "", 0, false
+	}
+
+	return s[:i], line, true
+}
+
+// parseBody splits the body of a log line into its message and attributes.
+// The attributes are the longest suffix of the body that consists solely of
+// space separated key-value pairs.
+func parseBody(body string) (string, []Attr) {
+	for i := 0; i < len(body); i++ {
+		if body[i] != ' ' {
+			continue
+		}
+
+		// Quickly skip positions that can't start an attribute.
+		if !strings.Contains(body[i:], "=") {
+			break
+		}
+
+		if attrs, ok := parseAttrs(body[i:]); ok {
+			return body[:i], attrs
+		}
+	}
+
+	return body, nil
+}
+
+// parseAttrs parses a sequence of attributes, each preceded by a space. False
+// is returned if s is not entirely made up of attributes.
+func parseAttrs(s string) ([]Attr, bool) {
+	var attrs []Attr
+	for len(s) > 0 {
+		if s[0] != ' ' {
+			return nil, false
+		}
+		s = s[1:]
+
+		key, rest, ok := parseToken(s)
+		if !ok || len(rest) == 0 || rest[0] != '=' {
+			return nil, false
+		}
+
+		value, rest, ok := parseToken(rest[1:])
+		if !ok {
+			return nil, false
+		}
+
+		attrs = append(attrs, Attr{Key: key, Value: value})
+		s = rest
+	}
+
+	return attrs, len(attrs) > 0
+}
+
+// parseToken parses either a quoted string or a bare word from the start of
+// s and returns it along with the remainder of s. A bare word ends at the
+// first space or '=' and may not contain quotes.
+func parseToken(s string) (string, string, bool) {
+	if len(s) == 0 {
+		return "", s, false
+	}
+
+	if s[0] == '"' {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", s, false
+		}
+
+		unquoted, err := strconv.Unquote(quoted)
+		if err != nil {
+			return "", s, false
+		}
+
+		return unquoted, s[len(quoted):], true
+	}
+
+	end := strings.IndexAny(s, " =\"\n")
+	if end < 0 {
+		end = len(s)
+	}
+	if end == 0 || (end < len(s) && s[end] == '"') {
+		return "", s, false
+	}
+
+	return s[:end], s[end:], true
+}
+
+// stripANSI removes all terminal escape sequences of the form ESC [ ... final
+// byte from s.
+func stripANSI(s string) string {
+	if strings.IndexByte(s, '\x1b') < 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\x1b' || i+1 >= len(s) || s[i+1] != '[' {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		// Skip the parameter bytes up to and including the final byte.
+		i += 2
+		for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+			i++
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,215 @@
+package logreader
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btclog/v2"
+)
+
+// TestParseLine tests that single log lines are parsed into the expected
+// records.
+func TestParseLine(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2024, 10, 3, 13, 34, 17, 123e6, time.UTC)
+
+	tests := []struct {
+		name     string
+		line     string
+		expected *Record
+		err      error
+	}{
+		{
+			name: "full header",
+			line: "2024-10-03 13:34:17.123 [DBG] PEER " +
+				"peer.go:42: Connected to peer " +
+				"addr=1.2.3.4:8333",
+			expected: &Record{
+				Time:    ts,
+				Level:   btclog.LevelDebug,
+				Tag:     "PEER",
+				File:    "peer.go",
+				Line:    42,
+				Message: "Connected to peer",
+				Attrs:   []Attr{{"addr", "1.2.3.4:8333"}},
+			},
+		},
+		{
+			name: "no timestamp or tag",
+			line: "[INF]: Test msg",
+			expected: &Record{
+				Level:   btclog.LevelInfo,
+				Message: "Test msg",
+			},
+		},
+		{
+			name: "v1 without tag",
+			line: "2024-10-03 13:34:17.123 [WRN] : msg: with colon",
+			expected: &Record{
+				Time:    ts,
+				Level:   btclog.LevelWarn,
+				Message: "msg: with colon",
+			},
+		},
+		{
+			name: "quoted keys and values",
+			line: `[ERR] SRVR: Failed "the key"="a \"b\"" ` +
+				`empty="" err="no\nway"`,
+			expected: &Record{
+				Level:   btclog.LevelError,
+				Tag:     "SRVR",
+				Message: "Failed",
+				Attrs: []Attr{
+					{"the key", `a "b"`},
+					{"empty", ""},
+					{"err", "no\nway"},
+				},
+			},
+		},
+		{
+			name: "message with equals sign",
+			line: "[INF] RPCS: a=b is not c k=v",
+			expected: &Record{
+				Level:   btclog.LevelInfo,
+				Tag:     "RPCS",
+				Message: "a=b is not c",
+				Attrs:   []Attr{{"k", "v"}},
+			},
+		},
+		{
+			name: "empty message",
+			line: "[INF] RPCS:  k=v",
+			expected: &Record{
+				Level: btclog.LevelInfo,
+				Tag:   "RPCS",
+				Attrs: []Attr{{"k", "v"}},
+			},
+		},
+		{
+			name: "colored",
+			line: "\x1b[2m2024-10-03 13:34:17.123\x1b[0m " +
+				"\x1b[31m[ERR]\x1b[0m PEER: msg " +
+				"\x1b[2mk\x1b[0m=v",
+			expected: &Record{
+				Time:    ts,
+				Level:   btclog.LevelError,
+				Tag:     "PEER",
+				Message: "msg",
+				Attrs:   []Attr{{"k", "v"}},
+			},
+		},
+		{
+			name: "continuation",
+			line: "  at main.go:12",
+			err:  ErrNotHeader,
+		},
+		{
+			name: "unknown level",
+			line: "[FOO] PEER: msg",
+			err:  ErrNotHeader,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec, err := ParseLine(test.line, time.UTC)
+			if err != test.err {
+				t.Fatalf("Expected error %v, got %v",
+					test.err, err)
+			}
+			if !reflect.DeepEqual(rec, test.expected) {
+				t.Fatalf("Expected %+v, got %+v", test.expected,
+					rec)
+			}
+		})
+	}
+}
+
+// TestScannerRoundTrip tests that the output of the DefaultHandler, including
+// multi-line messages, can be read back by a Scanner and formatted again.
+func TestScannerRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2024, 10, 3, 13, 34, 17, 123e6, time.UTC)
+
+	var buf bytes.Buffer
+	buf.WriteString("orphaned line\n")
+
+	h := btclog.NewDefaultHandler(&buf, btclog.WithTimeSource(
+		func() time.Time {
+			return ts
+		},
+	))
+	log := btclog.NewSLogger(h.SubSystem("PEER"))
+	log.SetLevel(btclog.LevelTrace)
+
+	ctx := context.Background()
+	log.Infof("Line one\nline two")
+	log.InfoS(ctx, "Quoted", "a b", "c=d", "multi", "1\n2")
+	log.TraceS(ctx, "Last", "n", 3)
+
+	out := buf.String()
+	s := NewScanner(strings.NewReader(out), WithLocation(time.UTC))
+
+	var recs []*Record
+	for s.Next() {
+		recs = append(recs, s.Record())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Unable to scan: %v", err)
+	}
+
+	if len(recs) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(recs))
+	}
+	if recs[0].Message != "Line one\nline two" || recs[0].Attrs != nil {
+		t.Fatalf("Unexpected multi-line record: %+v", recs[0])
+	}
+	if v, _ := recs[1].Attr("a b"); v != "c=d" {
+		t.Fatalf("Expected quoted attribute, got %+v", recs[1])
+	}
+	if !recs[2].Time.Equal(ts) || recs[2].Level != btclog.LevelTrace {
+		t.Fatalf("Unexpected header: %+v", recs[2])
+	}
+
+	lines := strings.Split(out, "\n")
+	if recs[1].String() != lines[3] {
+		t.Fatalf("Expected %q, got %q", lines[3], recs[1].String())
+	}
+}
+
+// TestScannerJSONContinuation tests that continuation lines of a text record
+// that happen to be JSON objects, such as a logged request body, are kept in
+// the record's message instead of being read as JSON records.
+func TestScannerJSONContinuation(t *testing.T) {
+	t.Parallel()
+
+	in := "2024-10-03 15:34:17.123 [INF] RPCS: Request body:\n" +
+		"{\"amount\":5}\n" +
+		`{"level":"DBG","subsystem":"PEER","msg":"JSON record"}` + "\n"
+
+	s := NewScanner(strings.NewReader(in), WithLocation(time.UTC))
+
+	var recs []*Record
+	for s.Next() {
+		recs = append(recs, s.Record())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Unable to scan: %v", err)
+	}
+
+	if len(recs) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(recs))
+	}
+	if recs[0].Message != "Request body:\n{\"amount\":5}" {
+		t.Fatalf("Unexpected multi-line record: %+v", recs[0])
+	}
+	if recs[1].Tag != "PEER" || recs[1].Message != "JSON record" {
+		t.Fatalf("Unexpected JSON record: %+v", recs[1])
+	}
+}
@@ -0,0 +1,151 @@
+// Package logreader parses the text output of the btclog DefaultHandler, as
+// well as that of the v1 Backend, back into structured records.
+//
+// Each log line has the form
+//
+//	YYYY-MM-DD hh:mm:ss.sss [LVL] TAG file:line: msg k=v ...
+//
+// where the timestamp, sub-system tag, call-site and attributes are optional.
+// Keys and values that contain spaces or special characters are quoted with
+// strconv.Quote. Messages that contain newlines continue on the following
+// lines which do not start with a header.
+package logreader
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btclog"
+)
+
+// timestampFormat is the layout of the timestamp written by the DefaultHandler
+// and the v1 Backend.
+const timestampFormat = "2006-01-02 15:04:05.000"
+
+// Attr is a single key-value attribute of a log record. Both the key and the
+// value are unquoted.
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// Record is a single parsed log record.
+type Record struct {
+	// Time is the time of the record. It is the zero time if the log line
+	// did not include a timestamp.
+	Time time.Time
+
+	// Level is the level of the record.
+	Level btclog.Level
+
+	// Tag is the sub-system tag of the record, if any.
+	Tag string
+
+	// File and Line are the call-site of the record, if it was logged.
+	File string
+	Line int
+
+	// Message is the log message. Messages that span multiple lines are
+	// joined with newlines.
+	Message string
+
+	// Attrs holds the attributes of the record in the order in which they
+	// appeared.
+	Attrs []Attr
+}
+
+// Attr returns the value of the first attribute with the given key and true,
+// or false if there is no such attribute.
+func (r *Record) Attr(key string) (string, bool) {
+	for _, a := range r.Attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// CallSite returns the call-site of the record in the form file:line, or an
+// empty string if the record has no call-site.
+func (r *Record) CallSite() string {
+	if r.File == "" {
+		return ""
+	}
+
+	return r.File + ":" + strconv.Itoa(r.Line)
+}
+
+// String formats the record in the same way as the DefaultHandler. The
+// timestamp is written in the location of the record's time.
+func (r *Record) String() string {
+	var sb strings.Builder
+
+	if !r.Time.IsZero() {
+		sb.WriteString(r.Time.Format(timestampFormat))
+		sb.WriteByte(' ')
+	}
+
+	sb.WriteString("[" + r.Level.String() + "]")
+	if r.Tag != "" {
+		sb.WriteString(" " + r.Tag)
+	}
+	if r.File != "" {
+		sb.WriteString(" " + r.CallSite())
+	}
+	sb.WriteString(": ")
+	sb.WriteString(r.Message)
+
+	for _, a := range r.Attrs {
+		sb.WriteByte(' ')
+		sb.WriteString(Quote(a.Key))
+		sb.WriteByte('=')
+		sb.WriteString(Quote(a.Value))
+	}
+
+	return sb.String()
+}
+
+// Quote returns s quoted with strconv.Quote if the DefaultHandler would have
+// quoted it, and s itself otherwise.
+func Quote(s string) string {
+	if needsQuoting(s) {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// Copied from log/slog/text_handler.go.
+//
+// needsQuoting returns true if the given strings should be wrapped in quotes.
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			// Quote anything except a backslash that would need
+			// quoting in a JSON string, as well as space and '='.
+			if b != '\\' && (b == ' ' || b == '=' || b == '"' ||
+				b < ' ') {
+
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || unicode.IsSpace(r) ||
+			!unicode.IsPrint(r) {
+
+			return true
+		}
+		i += size
+	}
+	return false
+}
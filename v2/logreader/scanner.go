@@ -0,0 +1,157 @@
+package logreader
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxLineSize is the maximum size of a single log line that can be read by a
+// Scanner.
+const maxLineSize = 1 << 20
+
+// Option is the signature of a functional option that can be used to modify
+// the behaviour of a Scanner.
+type Option func(*scannerOpts)
+
+// scannerOpts holds options that can be modified by an Option.
+type scannerOpts struct {
+	loc *time.Location
+}
+
+// defaultScannerOpts returns the default Scanner options.
+func defaultScannerOpts() *scannerOpts {
+	return &scannerOpts{
+		loc: time.Local,
+	}
+}
+
+// WithLocation sets the location in which the timestamps of the log lines are
+// interpreted. By default, the local time zone is used which matches the
+// timestamps written by the DefaultHandler.
+func WithLocation(loc *time.Location) Option {
+	return func(o *scannerOpts) {
+		o.loc = loc
+	}
+}
+
+// Scanner reads log records from an io.Reader one at a time. Both the text
+// format of the DefaultHandler and the JSON format of the JSONHandler are
+// understood, even if they are mixed. Text lines that do not start with a log
+// header are treated as the continuation of the previous record's message.
+// Any such lines before the first header are skipped.
+//
+// A typical use looks like:
+//
+//	s := logreader.NewScanner(r)
+//	for s.Next() {
+//		rec := s.Record()
+//		...
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type Scanner struct {
+	opts  *scannerOpts
+	lines *bufio.Scanner
+
+	// pending is the record whose header has been read but which may still
+	// be continued on the following lines. If complete is true, the
+	// pending record was read from a JSON line and can't be continued.
+	pending  *Record
+	complete bool
+	body     strings.Builder
+
+	rec  *Record
+	err  error
+	done bool
+}
+
+// NewScanner creates a new Scanner that reads log records from r.
+func NewScanner(r io.Reader, options ...Option) *Scanner {
+	opts := defaultScannerOpts()
+	for _, o := range options {
+		o(opts)
+	}
+
+	lines := bufio.NewScanner(r)
+	lines.Buffer(nil, maxLineSize)
+
+	return &Scanner{
+		opts:  opts,
+		lines: lines,
+	}
+}
+
+// Next advances the Scanner to the next record, which is then available
+// through Record. It returns false when there are no more records, either
+// because the end of the input was reached or because of an error.
+func (s *Scanner) Next() bool {
+	s.rec = nil
+	if s.done {
+		return false
+	}
+
+	for s.lines.Scan() {
+		line := s.lines.Text()
+
+		rec, body, err := parseHeader(line, s.opts.loc)
+		complete := false
+		if err != nil && strings.HasPrefix(line, "{") {
+			rec, err = ParseJSONLine(line, s.opts.loc)
+			complete = true
+		}
+		if err != nil {
+			// Continuation lines are added to a pending text
+			// record while any other lines are skipped.
+			if s.pending != nil && !s.complete {
+				s.body.WriteByte('\n')
+				s.body.WriteString(stripANSI(line))
+			}
+			continue
+		}
+
+		prev := s.flush()
+		s.pending, s.complete = rec, complete
+		s.body.WriteString(body)
+
+		if prev != nil {
+			s.rec = prev
+			return true
+		}
+	}
+
+	s.done = true
+	s.err = s.lines.Err()
+	s.rec = s.flush()
+
+	return s.rec != nil
+}
+
+// Record returns the record that was read by the last call to Next.
+func (s *Scanner) Record() *Record {
+	return s.rec
+}
+
+// Err returns the first error that was encountered while reading the input.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// flush completes the pending record, if any, by parsing its accumulated body
+// and returns it.
+func (s *Scanner) flush() *Record {
+	rec := s.pending
+	if rec == nil {
+		return nil
+	}
+
+	if !s.complete {
+		rec.Message, rec.Attrs = parseBody(s.body.String())
+	}
+	s.pending = nil
+	s.body.Reset()
+
+	return rec
+}
@@ -0,0 +1,197 @@
+package btclog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/btcsuite/btclog"
+)
+
+// MultiHandler is a Handler that forwards each log record to several child
+// Handlers. Each child keeps its own logging level so that, for example, a
+// terminal can receive INF logs while a file receives DBG logs.
+type MultiHandler struct {
+	handlers []Handler
+}
+
+// A compile-time check to ensure that MultiHandler implements Handler.
+var _ Handler = (*MultiHandler)(nil)
+
+// NewMultiHandler creates a new Handler that forwards all log records to each
+// of the given Handlers.
+func NewMultiHandler(handlers ...Handler) *MultiHandler {
+	return &MultiHandler{
+		handlers: append([]Handler(nil), handlers...),
+	}
+}
+
+// Level returns the lowest logging level of all the child Handlers.
+//
+// NOTE: This is part of the Handler interface.
+func (m *MultiHandler) Level() btclog.Level {
+	level := LevelOff
+	for _, h := range m.handlers {
+		if l := h.Level(); l < level {
+			level = l
+		}
+	}
+
+	return level
+}
+
+// SetLevel changes the logging level of each of the child Handlers to the
+// passed level. Levels of individual children can still be set by calling
+// SetLevel on the child directly.
+//
+// NOTE: This is part of the Handler interface.
+func (m *MultiHandler) SetLevel(level btclog.Level) {
+	for _, h := range m.handlers {
+		h.SetLevel(level)
+	}
+}
+
+// Enabled reports whether any of the child Handlers handle records at the
+// given level.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (m *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handle passes the Record to each of the child Handlers that are enabled for
+// the Record's level. A failure of one child does not prevent the Record from
+// being passed to the others, instead all errors are joined and returned.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (m *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+
+		if err := h.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a new Handler with the given attributes added to each of
+// the child Handlers.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (m *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return m.with(func(h Handler) slog.Handler {
+		return h.WithAttrs(attrs)
+	})
+}
+
+// WithGroup returns a new Handler with the given group added to each of the
+// child Handlers.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (m *MultiHandler) WithGroup(name string) slog.Handler {
+	return m.with(func(h Handler) slog.Handler {
+		return h.WithGroup(name)
+	})
+}
+
+// SubSystem returns a copy of the given handler with each of the child
+// Handlers replaced by a copy with the new tag.
+//
+// NOTE: This is part of the Handler interface.
+func (m *MultiHandler) SubSystem(tag string) Handler {
+	return m.with(func(h Handler) slog.Handler {
+		return h.SubSystem(tag)
+	})
+}
+
+// with returns a new MultiHandler with each child replaced by the result of
+// applying fn to it. A child for which fn returns a plain slog.Handler, such
+// as a Handler that wraps a third-party slog.Handler, is kept by wrapping the
+// result in a plainHandler.
+func (m *MultiHandler) with(fn func(Handler) slog.Handler) *MultiHandler {
+	handlers := make([]Handler, 0, len(m.handlers))
+	for _, h := range m.handlers {
+		handlers = append(handlers, asHandler(fn(h), h))
+	}
+
+	return &MultiHandler{handlers: handlers}
+}
+
+// asHandler returns h as a Handler. If it is a plain slog.Handler, it is
+// wrapped in a plainHandler that takes its level from the given Handler that
+// h was derived from.
+func asHandler(h slog.Handler, origin Handler) Handler {
+	if handler, ok := h.(Handler); ok {
+		return handler
+	}
+
+	if p, ok := origin.(*plainHandler); ok {
+		origin = p.origin
+	}
+
+	return &plainHandler{Handler: h, origin: origin}
+}
+
+// plainHandler is a Handler for a plain slog.Handler that was derived from a
+// Handler via WithAttrs or WithGroup. The slog.Handler decides which records
+// it handles while the level is reported and changed through the Handler it
+// was derived from.
+type plainHandler struct {
+	slog.Handler
+
+	// origin is the Handler that the slog.Handler was derived from.
+	origin Handler
+}
+
+// A compile-time check to ensure that plainHandler implements Handler.
+var _ Handler = (*plainHandler)(nil)
+
+// Level returns the current logging level of the Handler that the slog.Handler
+// was derived from.
+//
+// NOTE: This is part of the Handler interface.
+func (p *plainHandler) Level() btclog.Level {
+	return p.origin.Level()
+}
+
+// SetLevel changes the logging level of the Handler that the slog.Handler was
+// derived from to the passed level.
+//
+// NOTE: This is part of the Handler interface.
+func (p *plainHandler) SetLevel(level btclog.Level) {
+	p.origin.SetLevel(level)
+}
+
+// WithAttrs returns a new Handler with the given attributes added.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (p *plainHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return asHandler(p.Handler.WithAttrs(attrs), p.origin)
+}
+
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (p *plainHandler) WithGroup(name string) slog.Handler {
+	return asHandler(p.Handler.WithGroup(name), p.origin)
+}
+
+// SubSystem returns the handler as is, since a plain slog.Handler has no
+// sub-system tag.
+//
+// NOTE: This is part of the Handler interface.
+func (p *plainHandler) SubSystem(_ string) Handler {
+	return p
+}
@@ -0,0 +1,159 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/btcsuite/btclog"
+)
+
+// errWriter is an io.Writer that always fails.
+type errWriter struct {
+	err error
+}
+
+// Write returns the errWriter's error.
+func (e *errWriter) Write([]byte) (int, error) {
+	return 0, e.err
+}
+
+// TestMultiHandler tests that the MultiHandler forwards records to each child
+// that is enabled for the record's level.
+func TestMultiHandler(t *testing.T) {
+	t.Parallel()
+
+	var textBuf, jsonBuf bytes.Buffer
+	text := NewDefaultHandler(&textBuf, WithNoTimestamp())
+	json := NewJSONHandler(&jsonBuf, WithNoTimestamp())
+
+	multi := NewMultiHandler(text, json)
+	multi.SetLevel(LevelInfo)
+	json.SetLevel(LevelDebug)
+
+	if multi.Level() != LevelDebug {
+		t.Fatalf("Expected level %s, got %s", LevelDebug,
+			multi.Level())
+	}
+
+	log := NewSLogger(multi.SubSystem("SUBS"))
+	log.Debugf("Debug %d", 1)
+	log.InfoS(context.Background(), "Info", "key", "value")
+
+	expectedText := `[INF] SUBS: Info key=value
+`
+	if textBuf.String() != expectedText {
+		t.Fatalf("Text result mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedText, textBuf.String())
+	}
+
+	expectedJSON := `{"level":"DBG","subsystem":"SUBS","msg":"Debug 1"}
+{"level":"INF","subsystem":"SUBS","msg":"Info","key":"value"}
+`
+	if jsonBuf.String() != expectedJSON {
+		t.Fatalf("JSON result mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedJSON, jsonBuf.String())
+	}
+}
+
+// TestMultiHandlerErrors tests that an error from one child does not prevent
+// the record from reaching the others and that all errors are returned.
+func TestMultiHandlerErrors(t *testing.T) {
+	t.Parallel()
+
+	errA, errB := errors.New("a"), errors.New("b")
+
+	var buf bytes.Buffer
+	multi := NewMultiHandler(
+		NewDefaultHandler(&errWriter{errA}, WithNoTimestamp()),
+		NewDefaultHandler(&buf, WithNoTimestamp()),
+		NewDefaultHandler(&errWriter{errB}, WithNoTimestamp()),
+	)
+
+	var r slog.Record
+	r.Level = slog.LevelInfo
+	r.Message = "Info"
+
+	err := multi.Handle(context.Background(), r)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("Expected joined error, got %v", err)
+	}
+
+	if buf.String() != "[INF]: Info\n" {
+		t.Fatalf("Unexpected log output: %q", buf.String())
+	}
+}
+
+// slogTextHandler is a Handler whose WithAttrs and WithGroup return a plain
+// slog.Handler, as is the case for a wrapped third-party slog.Handler.
+type slogTextHandler struct {
+	*slog.TextHandler
+
+	level *slog.LevelVar
+}
+
+// Level returns the current logging level of the handler.
+func (s *slogTextHandler) Level() btclog.Level {
+	return fromSlogLevel(s.level.Level())
+}
+
+// SetLevel changes the logging level of the handler.
+func (s *slogTextHandler) SetLevel(level btclog.Level) {
+	s.level.Set(toSlogLevel(level))
+}
+
+// SubSystem returns the handler as is.
+func (s *slogTextHandler) SubSystem(string) Handler {
+	return s
+}
+
+// TestMultiHandlerPlainChild tests that a child whose WithAttrs and WithGroup
+// return a plain slog.Handler is kept and that its level can still be set.
+func TestMultiHandlerPlainChild(t *testing.T) {
+	t.Parallel()
+
+	var textBuf, plainBuf bytes.Buffer
+	level := new(slog.LevelVar)
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	plain := &slogTextHandler{
+		TextHandler: slog.NewTextHandler(&plainBuf, opts),
+		level:       level,
+	}
+
+	multi := NewMultiHandler(
+		NewDefaultHandler(&textBuf, WithNoTimestamp()), plain,
+	)
+	derived := multi.WithAttrs([]slog.Attr{slog.Int("a", 1)}).(Handler)
+	derived = derived.WithGroup("g").(Handler).SubSystem("SUBS")
+
+	derived.SetLevel(LevelWarn)
+	if derived.Level() != LevelWarn || plain.Level() != LevelWarn {
+		t.Fatalf("Expected level %s, got %s and %s", LevelWarn,
+			derived.Level(), plain.Level())
+	}
+
+	log := NewSLogger(derived)
+	log.InfoS(context.Background(), "Dropped")
+	log.WarnS(context.Background(), "Kept", nil, "b", 2)
+
+	expectedText := "[WRN] SUBS: Kept a=1 g.b=2\n"
+	if textBuf.String() != expectedText {
+		t.Fatalf("Expected %q, got %q", expectedText, textBuf.String())
+	}
+
+	expectedPlain := "level=WARN msg=Kept a=1 g.b=2\n"
+	if plainBuf.String() != expectedPlain {
+		t.Fatalf("Expected %q, got %q", expectedPlain,
+			plainBuf.String())
+	}
+}
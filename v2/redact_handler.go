@@ -0,0 +1,291 @@
+package btclog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/btcsuite/btclog"
+)
+
+// Redacted is the value that redacted attributes and message parts are
+// replaced with unless WithRedactHash is used.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are the key patterns that are redacted by a RedactHandler
+// if no others are given with WithRedactKeys.
+var DefaultRedactKeys = []string{
+	"*privkey", "*private_key", "*secret*", "macaroon*", "password",
+	"passphrase", "seed", "mnemonic",
+}
+
+// Sensitive is implemented by values that must never be logged. Attributes
+// with such a value are always redacted by a RedactHandler, whatever their key.
+type Sensitive interface {
+	// Sensitive is a marker method.
+	Sensitive()
+}
+
+// sensitiveValue wraps a value to mark it as Sensitive.
+type sensitiveValue struct {
+	value any
+}
+
+// Sensitive is a marker method.
+//
+// NOTE: This is part of the Sensitive interface.
+func (sensitiveValue) Sensitive() {}
+
+// String returns the wrapped value formatted as by slog.AnyValue so that the
+// value can be hashed if WithRedactHash is used.
+func (s sensitiveValue) String() string {
+	return slog.AnyValue(s.value).String()
+}
+
+// Secret returns an attribute with a value that is always redacted by a
+// RedactHandler.
+func Secret(key string, value any) slog.Attr {
+	return slog.Any(key, sensitiveValue{value: value})
+}
+
+// RedactOption is the signature of a functional option that can be used to
+// modify the behaviour of a RedactHandler.
+type RedactOption func(*redactOpts)
+
+// redactOpts holds options that can be modified by a RedactOption.
+type redactOpts struct {
+	// keys holds the lower case glob patterns of the keys of attributes
+	// that are redacted.
+	keys []string
+
+	// hash defines whether redacted values are replaced with a truncated
+	// hash instead of Redacted.
+	hash bool
+
+	// messagePatterns holds the patterns of message parts that are
+	// redacted.
+	messagePatterns []*regexp.Regexp
+}
+
+// WithRedactKeys sets the patterns of the keys of attributes that are redacted,
+// replacing DefaultRedactKeys. The patterns use the syntax of path.Match, for
+// example "*key" or "macaroon*", and are matched case-insensitively against the
+// key of an attribute without any group prefix.
+func WithRedactKeys(patterns ...string) RedactOption {
+	return func(o *redactOpts) {
+		o.keys = make([]string, 0, len(patterns))
+		for _, p := range patterns {
+			o.keys = append(o.keys, strings.ToLower(p))
+		}
+	}
+}
+
+// WithRedactHash replaces redacted values with a truncated SHA-256 hash, such
+// as "[sha256:9f86d081]", instead of Redacted. This way log lines that contain
+// the same secret can still be correlated. Note that low entropy secrets, such
+// as short passwords, can be recovered from their hash by brute force.
+func WithRedactHash() RedactOption {
+	return func(o *redactOpts) {
+		o.hash = true
+	}
+}
+
+// WithRedactMessages adds patterns of message parts that are redacted. This is
+// mostly useful for the messages of the f-style logging methods since values
+// are formatted into the message there rather than being passed as
+// attributes.
+func WithRedactMessages(patterns ...*regexp.Regexp) RedactOption {
+	return func(o *redactOpts) {
+		o.messagePatterns = append(o.messagePatterns, patterns...)
+	}
+}
+
+// RedactHandler is a Handler that redacts secrets before records are passed on
+// to the child Handler. The values of attributes whose key matches one of the
+// configured patterns and of attributes with a Sensitive value are replaced,
+// both for the attributes of a record and for those added with WithAttrs.
+// Optionally, parts of the message that match a regular expression are
+// replaced as well.
+type RedactHandler struct {
+	handler Handler
+	opts    *redactOpts
+}
+
+// A compile-time check to ensure that RedactHandler implements Handler.
+var _ Handler = (*RedactHandler)(nil)
+
+// NewRedactHandler creates a new RedactHandler that passes the redacted records
+// on to the given Handler.
+func NewRedactHandler(handler Handler, options ...RedactOption) *RedactHandler {
+	opts := &redactOpts{}
+	WithRedactKeys(DefaultRedactKeys...)(opts)
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &RedactHandler{
+		handler: handler,
+		opts:    opts,
+	}
+}
+
+// Level returns the current logging level of the child Handler.
+//
+// NOTE: This is part of the Handler interface.
+func (r *RedactHandler) Level() btclog.Level {
+	return r.handler.Level()
+}
+
+// SetLevel changes the logging level of the child Handler to the passed
+// level.
+//
+// NOTE: This is part of the Handler interface.
+func (r *RedactHandler) SetLevel(level btclog.Level) {
+	r.handler.SetLevel(level)
+}
+
+// Enabled reports whether the child Handler handles records at the given
+// level.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (r *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return r.handler.Enabled(ctx, level)
+}
+
+// Handle passes a redacted copy of the Record on to the child Handler.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (r *RedactHandler) Handle(ctx context.Context, rec slog.Record) error {
+	redacted := slog.NewRecord(
+		rec.Time, rec.Level, r.redactMessage(rec.Message), rec.PC,
+	)
+	rec.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(r.redactAttr(a))
+		return true
+	})
+
+	return r.handler.Handle(ctx, redacted)
+}
+
+// WithAttrs returns a new Handler with the given attributes added after they
+// have been redacted.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (r *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		redacted = append(redacted, r.redactAttr(a))
+	}
+
+	h := r.handler.WithAttrs(redacted)
+	handler, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return r.with(handler)
+}
+
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (r *RedactHandler) WithGroup(name string) slog.Handler {
+	h := r.handler.WithGroup(name)
+	handler, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return r.with(handler)
+}
+
+// SubSystem returns a copy of the given handler but with the new tag.
+//
+// NOTE: This is part of the Handler interface.
+func (r *RedactHandler) SubSystem(tag string) Handler {
+	return r.with(r.handler.SubSystem(tag))
+}
+
+// with returns a new RedactHandler with the options of the receiver that wraps
+// the given Handler.
+func (r *RedactHandler) with(handler Handler) *RedactHandler {
+	return &RedactHandler{
+		handler: handler,
+		opts:    r.opts,
+	}
+}
+
+// redactAttr returns the given attribute with its value redacted if its key
+// matches one of the patterns or if its value is Sensitive. The attributes of
+// groups are redacted individually.
+func (r *RedactHandler) redactAttr(a slog.Attr) slog.Attr {
+	// Sensitive values are checked before resolving since a LogValuer
+	// could otherwise hide them.
+	if _, ok := a.Value.Any().(Sensitive); ok {
+		return slog.String(a.Key, r.mask(a.Value.String()))
+	}
+
+	if r.matchKey(a.Key) {
+		return slog.String(a.Key, r.mask(a.Value.Resolve().String()))
+	}
+
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		if _, ok := a.Value.Any().(Sensitive); ok {
+			return slog.String(a.Key, r.mask(a.Value.String()))
+		}
+
+		return a
+	}
+
+	group := a.Value.Group()
+	redacted := make([]slog.Attr, 0, len(group))
+	for _, ga := range group {
+		redacted = append(redacted, r.redactAttr(ga))
+	}
+
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+}
+
+// matchKey returns true if the given key matches one of the patterns.
+func (r *RedactHandler) matchKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	key = strings.ToLower(key)
+	for _, pattern := range r.opts.keys {
+		// A malformed pattern never matches.
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactMessage returns the message with all parts that match one of the
+// message patterns redacted.
+func (r *RedactHandler) redactMessage(msg string) string {
+	for _, re := range r.opts.messagePatterns {
+		msg = re.ReplaceAllStringFunc(msg, r.mask)
+	}
+
+	return msg
+}
+
+// mask returns the value that the given secret is replaced with.
+func (r *RedactHandler) mask(secret string) string {
+	if !r.opts.hash {
+		return Redacted
+	}
+
+	hash := sha256.Sum256([]byte(secret))
+
+	return "[sha256:" + hex.EncodeToString(hash[:4]) + "]"
+}
@@ -0,0 +1,61 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"testing"
+)
+
+// TestRedactHandler tests that attributes with matching keys or sensitive
+// values and matching message parts are redacted.
+func TestRedactHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	redact := NewRedactHandler(
+		NewDefaultHandler(&buf, WithNoTimestamp()),
+		WithRedactKeys("*key", "macaroon", "password"),
+		WithRedactMessages(regexp.MustCompile(`xprv[0-9a-zA-Z]+`)),
+	)
+	log := NewSLogger(redact.SubSystem("RPCS"))
+	ctx := context.Background()
+
+	log.InfoS(ctx, "Unlocking", "Password", "hunter2", "wallet", "default")
+	log.InfoS(ctx, "Signing", slog.Group("node", "privKey", "abc",
+		"alias", "bob"), Secret("seed", []string{"abandon", "ability"}))
+	log.Infof("Imported %s for %s", "xprv9s21ZrQH143K", "alice")
+
+	macaroon := []slog.Attr{slog.String("macaroon", "0201")}
+	withMacaroon := NewSLogger(redact.WithAttrs(macaroon).(Handler))
+	withMacaroon.InfoS(ctx, "Authenticated", "user", "alice")
+
+	expected := `[INF] RPCS: Unlocking Password=[REDACTED] wallet=default
+[INF] RPCS: Signing node.privKey=[REDACTED] node.alias=bob seed=[REDACTED]
+[INF] RPCS: Imported [REDACTED] for alice
+[INF]: Authenticated macaroon=[REDACTED] user=alice
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+// TestRedactHandlerHash tests that redacted values can be replaced by a
+// truncated hash.
+func TestRedactHandlerHash(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	log := NewSLogger(NewRedactHandler(
+		NewDefaultHandler(&buf, WithNoTimestamp()), WithRedactHash(),
+	))
+
+	log.InfoS(context.Background(), "Unlocking", "password", "test",
+		"user", "alice")
+
+	expected := "[INF]: Unlocking password=[sha256:9f86d081] user=alice\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
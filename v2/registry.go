@@ -0,0 +1,229 @@
+package btclog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btclog"
+)
+
+var (
+	// ErrUnknownSubSystem is returned when a sub-system tag is referenced
+	// that has not been registered.
+	ErrUnknownSubSystem = errors.New("unknown sub-system")
+
+	// ErrInvalidLevel is returned when a string can't be interpreted as a
+	// log level.
+	ErrInvalidLevel = errors.New("invalid log level")
+
+	// ErrInvalidLevelSpec is returned when a level spec is malformed.
+	ErrInvalidLevelSpec = errors.New("invalid level spec")
+)
+
+// LevelSpec is the parsed form of a level spec string such as
+// "info,PEER=debug,SRVR=trace". The entry without a tag sets the level for all
+// sub-systems while the tagged entries override it for individual sub-systems.
+type LevelSpec struct {
+	// Default is the level to apply to all sub-systems, if one was given.
+	Default *btclog.Level
+
+	// SubSystems maps sub-system tags to the level to apply to them.
+	SubSystems map[string]btclog.Level
+}
+
+// ParseLevelSpec parses a comma separated level spec of the form
+// "[level][,tag=level]...". Each level is validated with LevelFromString.
+func ParseLevelSpec(spec string) (*LevelSpec, error) {
+	parsed := &LevelSpec{
+		SubSystems: make(map[string]btclog.Level),
+	}
+
+	if strings.TrimSpace(spec) == "" {
+		return nil, fmt.Errorf("%w: empty spec", ErrInvalidLevelSpec)
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			return nil, fmt.Errorf("%w: empty entry in %q",
+				ErrInvalidLevelSpec, spec)
+		}
+
+		tag, levelStr, hasTag := strings.Cut(entry, "=")
+		if !hasTag {
+			if parsed.Default != nil {
+				return nil, fmt.Errorf("%w: multiple default "+
+					"levels in %q", ErrInvalidLevelSpec,
+					spec)
+			}
+
+			level, err := parseLevel(entry)
+			if err != nil {
+				return nil, err
+			}
+			parsed.Default = &level
+
+			continue
+		}
+
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("%w: missing sub-system in %q",
+				ErrInvalidLevelSpec, entry)
+		}
+		if _, ok := parsed.SubSystems[tag]; ok {
+			return nil, fmt.Errorf("%w: sub-system %s specified "+
+				"more than once", ErrInvalidLevelSpec, tag)
+		}
+
+		level, err := parseLevel(strings.TrimSpace(levelStr))
+		if err != nil {
+			return nil, fmt.Errorf("sub-system %s: %w", tag, err)
+		}
+		parsed.SubSystems[tag] = level
+	}
+
+	return parsed, nil
+}
+
+// parseLevel converts the given string to a level, returning ErrInvalidLevel
+// if it is not recognised by LevelFromString.
+func parseLevel(s string) (btclog.Level, error) {
+	level, ok := LevelFromString(s)
+	if !ok {
+		return level, fmt.Errorf("%w: %q", ErrInvalidLevel, s)
+	}
+
+	return level, nil
+}
+
+// SubSystemRegistry creates and keeps track of the Logger of each sub-system
+// of an application so that their levels can be managed in one place. All
+// loggers are derived from a single Handler using Handler.SubSystem.
+type SubSystemRegistry struct {
+	handler Handler
+
+	mu      sync.RWMutex
+	loggers map[string]Logger
+}
+
+// NewSubSystemRegistry constructs a new SubSystemRegistry that creates its
+// loggers from the given Handler.
+func NewSubSystemRegistry(handler Handler) *SubSystemRegistry {
+	return &SubSystemRegistry{
+		handler: handler,
+		loggers: make(map[string]Logger),
+	}
+}
+
+// Logger returns the Logger for the given sub-system tag. If no Logger has
+// been registered for the tag yet, a new one is created and registered.
+func (r *SubSystemRegistry) Logger(tag string) Logger {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if logger, ok := r.loggers[tag]; ok {
+		return logger
+	}
+
+	logger := NewSLogger(r.handler.SubSystem(tag))
+	r.loggers[tag] = logger
+
+	return logger
+}
+
+// SubSystems returns the sorted tags of all registered sub-systems.
+func (r *SubSystemRegistry) SubSystems() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := make([]string, 0, len(r.loggers))
+	for tag := range r.loggers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+// Level returns the current level of the given sub-system. False is returned
+// if the sub-system is not registered.
+func (r *SubSystemRegistry) Level(tag string) (btclog.Level, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	logger, ok := r.loggers[tag]
+	if !ok {
+		return LevelOff, false
+	}
+
+	return logger.Level(), true
+}
+
+// SetLevel changes the level of the given sub-system. ErrUnknownSubSystem is
+// returned if the sub-system is not registered.
+func (r *SubSystemRegistry) SetLevel(tag string, level btclog.Level) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logger, ok := r.loggers[tag]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSubSystem, tag)
+	}
+	logger.SetLevel(level)
+
+	return nil
+}
+
+// SetLevels changes the level of all registered sub-systems.
+func (r *SubSystemRegistry) SetLevels(level btclog.Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, logger := range r.loggers {
+		logger.SetLevel(level)
+	}
+}
+
+// ApplyLevelSpec parses the given level spec (see ParseLevelSpec) and applies
+// it to the registered sub-systems. The spec is validated in full before any
+// level is changed, so either all levels are applied or none are.
+func (r *SubSystemRegistry) ApplyLevelSpec(spec string) error {
+	parsed, err := ParseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	return r.ApplyParsedLevelSpec(parsed)
+}
+
+// ApplyParsedLevelSpec applies an already parsed LevelSpec to the registered
+// sub-systems. The default level, if any, is applied to all sub-systems before
+// the per sub-system levels. If the spec references an unknown sub-system, an
+// error is returned and no level is changed.
+func (r *SubSystemRegistry) ApplyParsedLevelSpec(spec *LevelSpec) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Make sure all the sub-systems are known before applying anything.
+	for tag := range spec.SubSystems {
+		if _, ok := r.loggers[tag]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownSubSystem, tag)
+		}
+	}
+
+	if spec.Default != nil {
+		for _, logger := range r.loggers {
+			logger.SetLevel(*spec.Default)
+		}
+	}
+
+	for tag, level := range spec.SubSystems {
+		r.loggers[tag].SetLevel(level)
+	}
+
+	return nil
+}
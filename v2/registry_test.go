@@ -0,0 +1,138 @@
+package btclog
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btclog"
+)
+
+// TestParseLevelSpec tests that valid level specs are parsed correctly and
+// that invalid ones are rejected with the expected error.
+func TestParseLevelSpec(t *testing.T) {
+	t.Parallel()
+
+	debug := LevelDebug
+
+	tests := []struct {
+		spec        string
+		expected    *LevelSpec
+		expectedErr error
+	}{
+		{
+			spec: "debug",
+			expected: &LevelSpec{
+				Default:    &debug,
+				SubSystems: map[string]btclog.Level{},
+			},
+		},
+		{
+			spec: "dbg, PEER=trace,SRVR=off",
+			expected: &LevelSpec{
+				Default: &debug,
+				SubSystems: map[string]btclog.Level{
+					"PEER": LevelTrace,
+					"SRVR": LevelOff,
+				},
+			},
+		},
+		{
+			spec: "PEER=warn",
+			expected: &LevelSpec{
+				SubSystems: map[string]btclog.Level{
+					"PEER": LevelWarn,
+				},
+			},
+		},
+		{
+			spec:        "",
+			expectedErr: ErrInvalidLevelSpec,
+		},
+		{
+			spec:        "info,,PEER=debug",
+			expectedErr: ErrInvalidLevelSpec,
+		},
+		{
+			spec:        "info,debug",
+			expectedErr: ErrInvalidLevelSpec,
+		},
+		{
+			spec:        "=debug",
+			expectedErr: ErrInvalidLevelSpec,
+		},
+		{
+			spec:        "PEER=debug,PEER=info",
+			expectedErr: ErrInvalidLevelSpec,
+		},
+		{
+			spec:        "verbose",
+			expectedErr: ErrInvalidLevel,
+		},
+		{
+			spec:        "PEER=verbose",
+			expectedErr: ErrInvalidLevel,
+		},
+	}
+
+	for _, test := range tests {
+		spec, err := ParseLevelSpec(test.spec)
+		if !errors.Is(err, test.expectedErr) {
+			t.Fatalf("%q: expected error %v, got %v", test.spec,
+				test.expectedErr, err)
+		}
+
+		if !reflect.DeepEqual(spec, test.expected) {
+			t.Fatalf("%q: expected %+v, got %+v", test.spec,
+				test.expected, spec)
+		}
+	}
+}
+
+// TestSubSystemRegistry tests that the registry keeps track of its loggers
+// and applies level specs to them atomically.
+func TestSubSystemRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := NewSubSystemRegistry(NewDefaultHandler(io.Discard))
+	peer := r.Logger("PEER")
+	srvr := r.Logger("SRVR")
+
+	if r.Logger("PEER") != peer {
+		t.Fatalf("Expected the registered logger to be returned")
+	}
+
+	tags := r.SubSystems()
+	if !reflect.DeepEqual(tags, []string{"PEER", "SRVR"}) {
+		t.Fatalf("Unexpected sub-systems: %v", tags)
+	}
+
+	if err := r.ApplyLevelSpec("warn,SRVR=trace"); err != nil {
+		t.Fatalf("Unable to apply level spec: %v", err)
+	}
+	if peer.Level() != LevelWarn || srvr.Level() != LevelTrace {
+		t.Fatalf("Unexpected levels: PEER=%s, SRVR=%s", peer.Level(),
+			srvr.Level())
+	}
+
+	// A spec referencing an unknown sub-system must not change any of the
+	// levels.
+	err := r.ApplyLevelSpec("debug,RPCS=trace")
+	if !errors.Is(err, ErrUnknownSubSystem) {
+		t.Fatalf("Expected ErrUnknownSubSystem, got %v", err)
+	}
+	if peer.Level() != LevelWarn || srvr.Level() != LevelTrace {
+		t.Fatalf("Levels changed by failed spec: PEER=%s, SRVR=%s",
+			peer.Level(), srvr.Level())
+	}
+
+	if err := r.SetLevel("RPCS", LevelInfo); err == nil {
+		t.Fatalf("Expected error for unknown sub-system")
+	}
+
+	r.SetLevels(LevelError)
+	if level, ok := r.Level("PEER"); !ok || level != LevelError {
+		t.Fatalf("Unexpected PEER level: %s", level)
+	}
+}
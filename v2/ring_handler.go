@@ -0,0 +1,313 @@
+package btclog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/btcsuite/btclog"
+)
+
+// RingOption is the signature of a functional option that can be used to
+// modify the behaviour of a RingHandler.
+type RingOption func(*ringOpts)
+
+// ringOpts holds options that can be modified by a RingOption.
+type ringOpts struct {
+	// dumpOnCritical is the writer that the captured records are dumped
+	// to whenever a record with LevelCritical is handled. If nil, no
+	// automatic dump is done.
+	dumpOnCritical io.Writer
+
+	// dumpJSON defines whether automatic dumps use the JSON format rather
+	// than the text format.
+	dumpJSON bool
+
+	// dumpOpts are the HandlerOptions used to format automatic dumps.
+	dumpOpts []HandlerOption
+}
+
+// WithDumpOnCritical instructs the RingHandler to dump all captured records in
+// the DefaultHandler text format to w whenever a record with LevelCritical is
+// handled. The given HandlerOptions are used to format the dump.
+func WithDumpOnCritical(w io.Writer, opts ...HandlerOption) RingOption {
+	return func(o *ringOpts) {
+		o.dumpOnCritical = w
+		o.dumpJSON = false
+		o.dumpOpts = opts
+	}
+}
+
+// WithJSONDumpOnCritical is like WithDumpOnCritical but dumps the captured
+// records in the JSONHandler format.
+func WithJSONDumpOnCritical(w io.Writer, opts ...HandlerOption) RingOption {
+	return func(o *ringOpts) {
+		o.dumpOnCritical = w
+		o.dumpJSON = true
+		o.dumpOpts = opts
+	}
+}
+
+// ringOp is an operation that was applied to a RingHandler via WithAttrs or
+// WithGroup. The operations are replayed on the dump Handler so that dumped
+// records look the same as they would have in the primary output.
+type ringOp struct {
+	group string
+	attrs []slog.Attr
+}
+
+// ringEntry is a single captured record along with the sub-system tag and
+// operations of the RingHandler that captured it.
+type ringEntry struct {
+	record slog.Record
+	tag    string
+	ops    []ringOp
+}
+
+// ring is a fixed size circular buffer of captured records. It is shared by a
+// RingHandler and all the Handlers derived from it.
+type ring struct {
+	opts *ringOpts
+
+	// captureLevel is the slog.Level at or above which records are
+	// captured.
+	captureLevel int64
+
+	mu      sync.Mutex
+	entries []ringEntry
+	next    int
+	full    bool
+}
+
+// add stores the given entry, overwriting the oldest one if the ring is full.
+func (r *ring) add(e ringEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = e
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// snapshot returns a copy of all entries in the ring, oldest first.
+func (r *ring) snapshot() []ringEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]ringEntry(nil), r.entries[:r.next]...)
+	}
+
+	entries := make([]ringEntry, 0, len(r.entries))
+	entries = append(entries, r.entries[r.next:]...)
+
+	return append(entries, r.entries[:r.next]...)
+}
+
+// RingHandler is a Handler that keeps the last N log records in memory in
+// addition to passing them on to a primary Handler. Records are captured at a
+// capture level that is independent of, and usually lower than, the level of
+// the primary Handler. The captured records can be dumped in either the
+// DefaultHandler or the JSONHandler format at any time, for example when a
+// critical error occurs, when recovering from a panic or from an admin
+// endpoint.
+type RingHandler struct {
+	primary Handler
+	ring    *ring
+
+	tag string
+	ops []ringOp
+}
+
+// A compile-time check to ensure that RingHandler implements Handler.
+var _ Handler = (*RingHandler)(nil)
+
+// NewRingHandler creates a new RingHandler that passes records on to the
+// primary Handler and keeps the last size records at or above the capture
+// level in memory.
+func NewRingHandler(primary Handler, size int, captureLevel btclog.Level,
+	options ...RingOption) *RingHandler {
+
+	opts := &ringOpts{}
+	for _, o := range options {
+		o(opts)
+	}
+
+	if size < 1 {
+		size = 1
+	}
+
+	return &RingHandler{
+		primary: primary,
+		ring: &ring{
+			opts:         opts,
+			captureLevel: int64(toSlogLevel(captureLevel)),
+			entries:      make([]ringEntry, size),
+		},
+	}
+}
+
+// Level returns the current logging level of the primary Handler.
+//
+// NOTE: This is part of the Handler interface.
+func (r *RingHandler) Level() btclog.Level {
+	return r.primary.Level()
+}
+
+// SetLevel changes the logging level of the primary Handler to the passed
+// level. The capture level is not affected.
+//
+// NOTE: This is part of the Handler interface.
+func (r *RingHandler) SetLevel(level btclog.Level) {
+	r.primary.SetLevel(level)
+}
+
+// CaptureLevel returns the level at or above which records are captured.
+func (r *RingHandler) CaptureLevel() btclog.Level {
+	return fromSlogLevel(slog.Level(atomic.LoadInt64(&r.ring.captureLevel)))
+}
+
+// SetCaptureLevel changes the level at or above which records are captured.
+// This affects all Handlers derived from the same RingHandler.
+func (r *RingHandler) SetCaptureLevel(level btclog.Level) {
+	atomic.StoreInt64(&r.ring.captureLevel, int64(toSlogLevel(level)))
+}
+
+// Enabled reports whether the handler either captures or passes on records at
+// the given level.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (r *RingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return r.capturing(level) || r.primary.Enabled(ctx, level)
+}
+
+// Handle captures the Record if its level is at or above the capture level
+// and passes it on to the primary Handler if that is enabled for the level.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (r *RingHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if r.capturing(rec.Level) {
+		r.ring.add(ringEntry{
+			record: rec.Clone(),
+			tag:    r.tag,
+			ops:    r.ops,
+		})
+	}
+
+	var err error
+	if r.primary.Enabled(ctx, rec.Level) {
+		err = r.primary.Handle(ctx, rec)
+	}
+
+	opts := r.ring.opts
+	if opts.dumpOnCritical != nil && rec.Level >= levelCritical {
+		if opts.dumpJSON {
+			_ = r.DumpJSON(opts.dumpOnCritical, opts.dumpOpts...)
+		} else {
+			_ = r.DumpText(opts.dumpOnCritical, opts.dumpOpts...)
+		}
+	}
+
+	return err
+}
+
+// WithAttrs returns a new Handler with the given attributes added.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (r *RingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h := r.primary.WithAttrs(attrs)
+	primary, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return r.with(primary, r.tag, ringOp{attrs: attrs})
+}
+
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (r *RingHandler) WithGroup(name string) slog.Handler {
+	h := r.primary.WithGroup(name)
+	primary, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return r.with(primary, r.tag, ringOp{group: name})
+}
+
+// SubSystem returns a copy of the given handler but with the new tag. The
+// returned Handler captures records into the same ring.
+//
+// NOTE: This is part of the Handler interface.
+func (r *RingHandler) SubSystem(tag string) Handler {
+	return r.with(r.primary.SubSystem(tag), tag)
+}
+
+// DumpText writes all captured records, oldest first, to w in the
+// DefaultHandler text format. The given HandlerOptions are used to format the
+// records. Since each record carries the PC of its call-site, the call-site is
+// included if requested with WithCallerFlags.
+func (r *RingHandler) DumpText(w io.Writer, opts ...HandlerOption) error {
+	return r.dump(NewDefaultHandler(w, opts...))
+}
+
+// DumpJSON writes all captured records, oldest first, to w in the JSONHandler
+// format. The given HandlerOptions are used to format the records. Since each
+// record carries the PC of its call-site, the call-site is included if
+// requested with WithCallerFlags.
+func (r *RingHandler) DumpJSON(w io.Writer, opts ...HandlerOption) error {
+	return r.dump(NewJSONHandler(w, opts...))
+}
+
+// dump passes each captured record to a Handler derived from the given one
+// with the sub-system tag and operations that were in effect when the record
+// was captured.
+func (r *RingHandler) dump(base Handler) error {
+	for _, e := range r.ring.snapshot() {
+		var h slog.Handler = base.SubSystem(e.tag)
+		for _, op := range e.ops {
+			if op.attrs != nil {
+				h = h.WithAttrs(op.attrs)
+			} else {
+				h = h.WithGroup(op.group)
+			}
+		}
+
+		if err := h.Handle(context.Background(), e.record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// capturing returns true if records at the given level are captured.
+func (r *RingHandler) capturing(level slog.Level) bool {
+	return atomic.LoadInt64(&r.ring.captureLevel) <= int64(level)
+}
+
+// with returns a new RingHandler that shares the ring with the receiver and
+// wraps the given primary Handler. Any given operations are appended to the
+// receiver's existing operations.
+func (r *RingHandler) with(primary Handler, tag string,
+	ops ...ringOp) *RingHandler {
+
+	allOps := make([]ringOp, 0, len(r.ops)+len(ops))
+	allOps = append(allOps, r.ops...)
+	allOps = append(allOps, ops...)
+
+	return &RingHandler{
+		primary: primary,
+		ring:    r.ring,
+		tag:     tag,
+		ops:     allOps,
+	}
+}
@@ -0,0 +1,147 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestRingHandler tests that the RingHandler captures records below the level
+// of its primary Handler and that the captured records can be dumped.
+func TestRingHandler(t *testing.T) {
+	t.Parallel()
+
+	var primaryBuf, critBuf bytes.Buffer
+	primary := NewDefaultHandler(&primaryBuf, WithNoTimestamp())
+	ring := NewRingHandler(
+		primary, 3, LevelTrace,
+		WithDumpOnCritical(&critBuf, WithNoTimestamp()),
+	)
+
+	log := NewSLogger(ring.SubSystem("SUBS"))
+	log.Trace("Dropped from the ring")
+	log.Debug("Debug")
+	log.Info("Info")
+
+	grouped := slog.New(ring.SubSystem("GRPS")).With("a", 1).
+		WithGroup("g")
+	grouped.Log(context.Background(), levelTrace, "Trace", "b", 2)
+
+	expectedPrimary := "[INF] SUBS: Info\n"
+	if primaryBuf.String() != expectedPrimary {
+		t.Fatalf("Primary mismatch. Expected %q, got %q",
+			expectedPrimary, primaryBuf.String())
+	}
+
+	var textBuf bytes.Buffer
+	if err := ring.DumpText(&textBuf, WithNoTimestamp()); err != nil {
+		t.Fatalf("Unable to dump: %v", err)
+	}
+
+	expectedText := `[DBG] SUBS: Debug
+[INF] SUBS: Info
+[TRC] GRPS: Trace a=1 g.b=2
+`
+	if textBuf.String() != expectedText {
+		t.Fatalf("Text dump mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedText, textBuf.String())
+	}
+
+	var jsonBuf bytes.Buffer
+	if err := ring.DumpJSON(&jsonBuf, WithNoTimestamp()); err != nil {
+		t.Fatalf("Unable to dump: %v", err)
+	}
+
+	expectedJSON := `{"level":"DBG","subsystem":"SUBS","msg":"Debug"}
+{"level":"INF","subsystem":"SUBS","msg":"Info"}
+{"level":"TRC","subsystem":"GRPS","msg":"Trace","a":1,"g":{"b":2}}
+`
+	if jsonBuf.String() != expectedJSON {
+		t.Fatalf("JSON dump mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedJSON, jsonBuf.String())
+	}
+
+	// A critical record triggers an automatic dump.
+	log.Critical("Critical")
+
+	expectedCrit := `[INF] SUBS: Info
+[TRC] GRPS: Trace a=1 g.b=2
+[CRT] SUBS: Critical
+`
+	if critBuf.String() != expectedCrit {
+		t.Fatalf("Critical dump mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedCrit, critBuf.String())
+	}
+}
+
+// TestRingHandlerTimestamps tests that dumped records keep the time at which
+// they were originally logged.
+func TestRingHandlerTimestamps(t *testing.T) {
+	t.Parallel()
+
+	var primaryBuf bytes.Buffer
+	ring := NewRingHandler(NewDefaultHandler(&primaryBuf), 10, LevelDebug)
+
+	var r slog.Record
+	r.Time = time.Date(2024, 10, 3, 13, 34, 17, 123e6, time.UTC)
+	r.Level = levelDebug
+	r.Message = "Debug"
+	if err := ring.Handle(context.Background(), r); err != nil {
+		t.Fatalf("Unable to handle record: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ring.DumpText(&buf); err != nil {
+		t.Fatalf("Unable to dump: %v", err)
+	}
+
+	expected := "2024-10-03 13:34:17.123 [DBG]: Debug\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+// TestRingHandlerCallSite tests that dumped records include the call-site at
+// which they were originally logged if requested.
+func TestRingHandlerCallSite(t *testing.T) {
+	t.Parallel()
+
+	var primaryBuf bytes.Buffer
+	ring := NewRingHandler(NewDefaultHandler(&primaryBuf), 10, LevelDebug)
+	ring.SetLevel(LevelInfo)
+	log := NewSLogger(ring.SubSystem("SUBS"))
+
+	_, _, line, _ := runtime.Caller(0)
+	log.Debugf("Debug")
+
+	var textBuf, jsonBuf bytes.Buffer
+	err := ring.DumpText(
+		&textBuf, WithNoTimestamp(), WithCallerFlags(Lshortfile),
+	)
+	if err != nil {
+		t.Fatalf("Unable to dump: %v", err)
+	}
+	err = ring.DumpJSON(
+		&jsonBuf, WithNoTimestamp(), WithCallerFlags(Lshortfile),
+	)
+	if err != nil {
+		t.Fatalf("Unable to dump: %v", err)
+	}
+
+	site := "ring_handler_test.go:" + strconv.Itoa(line+1)
+
+	expectedText := "[DBG] SUBS " + site + ": Debug\n"
+	if textBuf.String() != expectedText {
+		t.Fatalf("Expected %q, got %q", expectedText, textBuf.String())
+	}
+
+	expectedJSON := `{"level":"DBG","subsystem":"SUBS","source":"` +
+		site + `","msg":"Debug"}` + "\n"
+	if jsonBuf.String() != expectedJSON {
+		t.Fatalf("Expected %q, got %q", expectedJSON, jsonBuf.String())
+	}
+}
@@ -0,0 +1,515 @@
+// Package rotator provides an io.Writer that writes to a log file which is
+// rolled over once it reaches a maximum size or after a fixed wall-clock
+// interval. It can be passed to the v2 DefaultHandler or to a v1 Backend.
+package rotator
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// backupTimeFormat is the layout of the timestamp that is appended to
+	// the name of a rolled file.
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+
+	// compressSuffix is the file name suffix of compressed backups.
+	compressSuffix = ".gz"
+)
+
+// ErrClosed is returned when writing to or rotating a closed Writer.
+var ErrClosed = errors.New("rotator: writer is closed")
+
+// Option is the signature of a functional option that can be used to modify
+// the behaviour of a Writer.
+type Option func(*options)
+
+// options holds the settings that can be modified by an Option.
+type options struct {
+	// maxSize is the size in bytes after which the log file is rolled.
+	// Zero means that the file is never rolled due to its size.
+	maxSize int64
+
+	// interval is the wall-clock interval after which the log file is
+	// rolled. Zero means that the file is never rolled due to its age.
+	interval time.Duration
+
+	// maxBackups is the number of rolled files to keep. Zero means that
+	// all rolled files are kept.
+	maxBackups int
+
+	// compress defines whether rolled files should be gzip compressed.
+	compress bool
+
+	// now is used to obtain the current time.
+	now func() time.Time
+
+	// rename is used to rename the log file to its backup name.
+	rename func(oldpath, newpath string) error
+}
+
+// defaultOptions constructs an options struct with default settings.
+func defaultOptions() *options {
+	return &options{
+		now:    time.Now,
+		rename: os.Rename,
+	}
+}
+
+// WithMaxSize sets the size in bytes after which the log file is rolled.
+func WithMaxSize(bytes int64) Option {
+	return func(opts *options) {
+		opts.maxSize = bytes
+	}
+}
+
+// WithInterval sets the wall-clock interval after which the log file is
+// rolled. Intervals are aligned to multiples of the duration since the zero
+// time, so an interval of 24 hours rolls the file at midnight UTC.
+func WithInterval(interval time.Duration) Option {
+	return func(opts *options) {
+		opts.interval = interval
+	}
+}
+
+// WithMaxBackups sets the number of rolled files to keep. Older files are
+// removed after each roll.
+func WithMaxBackups(n int) Option {
+	return func(opts *options) {
+		opts.maxBackups = n
+	}
+}
+
+// WithCompression enables gzip compression of rolled files. Compression is
+// done in the background so that it doesn't block writes.
+func WithCompression() Option {
+	return func(opts *options) {
+		opts.compress = true
+	}
+}
+
+// withClock overrides the source of the current time. It is used in tests.
+func withClock(now func() time.Time) Option {
+	return func(opts *options) {
+		opts.now = now
+	}
+}
+
+// withRename overrides the function that renames the log file to its backup
+// name. It is used in tests.
+func withRename(rename func(oldpath, newpath string) error) Option {
+	return func(opts *options) {
+		opts.rename = rename
+	}
+}
+
+// Writer is an io.WriteCloser that writes to a log file and rolls it over
+// according to its options. It is safe for concurrent use.
+type Writer struct {
+	filename string
+	opts     *options
+
+	mu sync.Mutex
+
+	// file is the current log file. It is nil if a previous roll failed
+	// to open a new log file, in which case opening it is retried on the
+	// next write.
+	file     *os.File
+	size     int64
+	deadline time.Time
+	closed   bool
+
+	// wg tracks the background compression goroutines.
+	wg sync.WaitGroup
+
+	// bgErr holds the first error encountered by a background
+	// compression goroutine.
+	bgErrMu sync.Mutex
+	bgErr   error
+}
+
+// A compile-time check to ensure that Writer implements io.WriteCloser.
+var _ io.WriteCloser = (*Writer)(nil)
+
+// New creates a new Writer that writes to the given file. If the file already
+// exists, new log lines are appended to it.
+func New(filename string, opts ...Option) (*Writer, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	w := &Writer{
+		filename: filename,
+		opts:     o,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Write writes p to the current log file, rolling it over first if required.
+//
+// NOTE: This is part of the io.Writer interface.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	// If the log file couldn't be rolled but is still open, the line is
+	// written to it anyway so that it isn't lost. Rolling is retried on
+	// the next write.
+	var rotateErr error
+	if w.shouldRotate(int64(len(p))) {
+		rotateErr = w.rotate()
+		if rotateErr != nil && w.file == nil {
+			return 0, rotateErr
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, errors.Join(rotateErr, err)
+}
+
+// Rotate rolls the current log file over immediately. This is useful to hook
+// up to an external trigger such as SIGHUP.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+
+	return w.rotate()
+}
+
+// Close closes the current log file and waits for any background compression
+// to finish. The first error encountered during background compression, if
+// any, is returned.
+//
+// NOTE: This is part of the io.Closer interface.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+
+	w.bgErrMu.Lock()
+	defer w.bgErrMu.Unlock()
+
+	return errors.Join(err, w.bgErr)
+}
+
+// shouldRotate returns true if the log file must be rolled before writing
+// the given number of bytes to it.
+//
+// NOTE: The mutex must be held when calling this method.
+func (w *Writer) shouldRotate(n int64) bool {
+	if w.opts.maxSize > 0 && w.size > 0 && w.size+n > w.opts.maxSize {
+		return true
+	}
+
+	return !w.deadline.IsZero() && !w.opts.now().Before(w.deadline)
+}
+
+// open opens the log file for appending, creating it and its directory if
+// required.
+//
+// NOTE: The mutex must be held when calling this method.
+func (w *Writer) open() error {
+	err := os.MkdirAll(filepath.Dir(w.filename), 0700)
+	if err != nil {
+		return fmt.Errorf("rotator: unable to create log "+
+			"directory: %w", err)
+	}
+
+	f, err := os.OpenFile(
+		w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600,
+	)
+	if err != nil {
+		return fmt.Errorf("rotator: unable to open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("rotator: unable to stat log file: %w", err)
+	}
+
+	w.file = f
+	w.size = info.Size()
+
+	if w.opts.interval > 0 {
+		now := w.opts.now()
+		w.deadline = now.Truncate(w.opts.interval).Add(w.opts.interval)
+	}
+
+	return nil
+}
+
+// rotate closes the current log file, renames it to a timestamped backup and
+// opens a new log file. Old backups are then removed and, if enabled, the new
+// backup is compressed in the background. If the log file can't be renamed, it
+// is reopened so that writes can continue to it. If no log file could be
+// opened, opening one is retried on the next write.
+//
+// NOTE: The mutex must be held when calling this method.
+func (w *Writer) rotate() error {
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	// The file is released even if Close returns an error, so it must
+	// not be used afterwards either way.
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("rotator: unable to close log file: %w", err)
+	}
+
+	backup := w.backupName()
+	if err := w.opts.rename(w.filename, backup); err != nil {
+		err = fmt.Errorf("rotator: unable to rename log file: %w", err)
+		return errors.Join(err, w.open())
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	if w.opts.compress {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+
+			if err := compress(backup); err != nil {
+				w.setBgErr(err)
+			}
+		}()
+	}
+
+	return w.removeOldBackups()
+}
+
+// backupName returns an unused name for a backup of the log file.
+//
+// NOTE: The mutex must be held when calling this method.
+func (w *Writer) backupName() string {
+	base := w.filename + "." + w.opts.now().Format(backupTimeFormat)
+
+	name := base
+	for i := 1; exists(name) || exists(name+compressSuffix); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	return name
+}
+
+// backups returns the names of all backups of the log file, oldest first. A
+// backup that is currently being compressed is only listed once. Only files
+// that are named like a backup, i.e. the name of the log file followed by a
+// timestamp and an optional sequence number, are listed.
+func (w *Writer) backups() ([]string, error) {
+	dir, base := filepath.Split(w.filename)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	type backup struct {
+		name string
+		time string
+		seq  int
+	}
+
+	seen := make(map[string]struct{}, len(entries))
+	backups := make([]backup, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), compressSuffix)
+		suffix, ok := strings.CutPrefix(name, base+".")
+		if !ok {
+			continue
+		}
+
+		ts, seq, ok := parseBackupSuffix(suffix)
+		if !ok {
+			continue
+		}
+
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		backups = append(backups, backup{
+			name: filepath.Join(dir, name),
+			time: ts,
+			seq:  seq,
+		})
+	}
+
+	// The timestamps sort chronologically as strings, while the sequence
+	// numbers must be compared as numbers so that "-10" sorts after "-9".
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].time != backups[j].time {
+			return backups[i].time < backups[j].time
+		}
+		return backups[i].seq < backups[j].seq
+	})
+
+	names := make([]string, len(backups))
+	for i, b := range backups {
+		names[i] = b.name
+	}
+
+	return names, nil
+}
+
+// parseBackupSuffix parses the part of a backup name that follows the name of
+// the log file, in the form of a timestamp in backupTimeFormat followed by an
+// optional "-N" sequence number. It returns the timestamp and the sequence
+// number, which is zero if there is none.
+func parseBackupSuffix(suffix string) (string, int, bool) {
+	if len(suffix) < len(backupTimeFormat) {
+		return "", 0, false
+	}
+
+	n := len(backupTimeFormat)
+	ts, rest := suffix[:n], suffix[n:]
+	if _, err := time.Parse(backupTimeFormat, ts); err != nil {
+		return "", 0, false
+	}
+	if rest == "" {
+		return ts, 0, true
+	}
+
+	digits, ok := strings.CutPrefix(rest, "-")
+	if !ok || digits == "" {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseUint(digits, 10, 31)
+	if err != nil || seq < 1 {
+		return "", 0, false
+	}
+
+	return ts, int(seq), true
+}
+
+// removeOldBackups removes the oldest backups so that at most maxBackups
+// remain.
+//
+// NOTE: The mutex must be held when calling this method.
+func (w *Writer) removeOldBackups() error {
+	if w.opts.maxBackups <= 0 {
+		return nil
+	}
+
+	names, err := w.backups()
+	if err != nil {
+		return fmt.Errorf("rotator: unable to list backups: %w", err)
+	}
+	if len(names) <= w.opts.maxBackups {
+		return nil
+	}
+
+	for _, name := range names[:len(names)-w.opts.maxBackups] {
+		for _, f := range []string{name, name + compressSuffix} {
+			err := os.Remove(f)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("rotator: unable to remove "+
+					"backup: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setBgErr records the given error if no other background error has been
+// recorded yet.
+func (w *Writer) setBgErr(err error) {
+	w.bgErrMu.Lock()
+	defer w.bgErrMu.Unlock()
+
+	if w.bgErr == nil {
+		w.bgErr = err
+	}
+}
+
+// compress gzip compresses the given file and removes the original. If the
+// file has already been removed, nothing is done.
+func compress(name string) error {
+	src, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("rotator: unable to open backup: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(
+		name+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600,
+	)
+	if err != nil {
+		return fmt.Errorf("rotator: unable to create compressed "+
+			"backup: %w", err)
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(name + compressSuffix)
+		return fmt.Errorf("rotator: unable to compress backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(name + compressSuffix)
+		return fmt.Errorf("rotator: unable to compress backup: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("rotator: unable to close compressed "+
+			"backup: %w", err)
+	}
+
+	return os.Remove(name)
+}
+
+// exists returns true if a file with the given name exists.
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
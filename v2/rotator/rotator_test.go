@@ -0,0 +1,279 @@
+package rotator
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readFile returns the content of the given file, decompressing it if it is a
+// compressed backup.
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("Unable to open %s: %v", name, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, compressSuffix) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("Unable to decompress %s: %v", name, err)
+		}
+		r = gz
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Unable to read %s: %v", name, err)
+	}
+
+	return string(b)
+}
+
+// TestRotateBySize tests that the log file is rolled once it reaches its
+// maximum size and that only the configured number of backups is kept.
+func TestRotateBySize(t *testing.T) {
+	t.Parallel()
+
+	logFile := filepath.Join(t.TempDir(), "logs", "test.log")
+
+	now := time.Date(2024, 10, 3, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	w, err := New(
+		logFile, WithMaxSize(10), WithMaxBackups(2), withClock(clock),
+	)
+	if err != nil {
+		t.Fatalf("Unable to create writer: %v", err)
+	}
+
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n",
+		"line 4\n"} {
+
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Unable to write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unable to close: %v", err)
+	}
+
+	if got := readFile(t, logFile); got != "line 4\n" {
+		t.Fatalf("Unexpected log file content: %q", got)
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatalf("Unable to list backups: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %v", backups)
+	}
+	if got := readFile(t, backups[0]); got != "line 2\n" {
+		t.Fatalf("Unexpected backup content: %q", got)
+	}
+	if got := readFile(t, backups[1]); got != "line 3\n" {
+		t.Fatalf("Unexpected backup content: %q", got)
+	}
+}
+
+// TestRotateByInterval tests that the log file is rolled once the wall-clock
+// interval has passed and that rolled files are compressed.
+func TestRotateByInterval(t *testing.T) {
+	t.Parallel()
+
+	logFile := filepath.Join(t.TempDir(), "test.log")
+
+	now := time.Date(2024, 10, 3, 23, 59, 0, 0, time.UTC)
+	clock := func() time.Time {
+		return now
+	}
+
+	w, err := New(
+		logFile, WithInterval(24*time.Hour), WithCompression(),
+		withClock(clock),
+	)
+	if err != nil {
+		t.Fatalf("Unable to create writer: %v", err)
+	}
+
+	if _, err := w.Write([]byte("day 1\n")); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := w.Write([]byte("day 2\n")); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("Unable to rotate: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unable to close: %v", err)
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatalf("Unable to list backups: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %v", backups)
+	}
+
+	for i, expected := range []string{"day 1\n", "day 2\n"} {
+		name := backups[i] + compressSuffix
+		if got := readFile(t, name); got != expected {
+			t.Fatalf("Unexpected backup content: %q", got)
+		}
+		if exists(backups[i]) {
+			t.Fatalf("Uncompressed backup %s not removed",
+				backups[i])
+		}
+	}
+
+	if _, err := w.Write([]byte("closed\n")); err != ErrClosed {
+		t.Fatalf("Expected ErrClosed, got %v", err)
+	}
+}
+
+// TestRotateRenameFailure tests that the log file is reopened if it can't be
+// renamed, so that writes continue to it and rolling succeeds once the
+// underlying problem clears.
+func TestRotateRenameFailure(t *testing.T) {
+	t.Parallel()
+
+	logFile := filepath.Join(t.TempDir(), "test.log")
+
+	now := time.Date(2024, 10, 3, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	errRename := errors.New("rename failed")
+	failRename := true
+	rename := func(oldpath, newpath string) error {
+		if failRename {
+			return errRename
+		}
+		return os.Rename(oldpath, newpath)
+	}
+
+	w, err := New(
+		logFile, WithMaxSize(10), withClock(clock), withRename(rename),
+	)
+	if err != nil {
+		t.Fatalf("Unable to create writer: %v", err)
+	}
+
+	if _, err := w.Write([]byte("line 1\n")); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+
+	// The roll fails, but the line is still written to the log file.
+	n, err := w.Write([]byte("line 2\n"))
+	if !errors.Is(err, errRename) {
+		t.Fatalf("Expected rename error, got %v", err)
+	}
+	if n != len("line 2\n") {
+		t.Fatalf("Expected line to be written, got %d bytes", n)
+	}
+	if err := w.Rotate(); !errors.Is(err, errRename) {
+		t.Fatalf("Expected rename error, got %v", err)
+	}
+
+	failRename = false
+	if _, err := w.Write([]byte("line 3\n")); err != nil {
+		t.Fatalf("Unable to write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unable to close: %v", err)
+	}
+
+	if got := readFile(t, logFile); got != "line 3\n" {
+		t.Fatalf("Unexpected log file content: %q", got)
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatalf("Unable to list backups: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %v", backups)
+	}
+	if got := readFile(t, backups[0]); got != "line 1\nline 2\n" {
+		t.Fatalf("Unexpected backup content: %q", got)
+	}
+}
+
+// TestBackupsIgnoreUnrelated tests that only files that are named like a
+// backup of the log file are listed as backups, and therefore removed.
+func TestBackupsIgnoreUnrelated(t *testing.T) {
+	t.Parallel()
+
+	// Glob metacharacters in the path must not affect the listing.
+	dir := filepath.Join(t.TempDir(), "[logs]")
+	logFile := filepath.Join(dir, "test.log")
+
+	w, err := New(logFile, WithMaxBackups(1))
+	if err != nil {
+		t.Fatalf("Unable to create writer: %v", err)
+	}
+	defer w.Close()
+
+	unrelated := []string{
+		"test.log.old", "test.log.2024-10-03", "test.log.bak.gz",
+		"test.log.2024-10-03T00-00-00.000-x",
+		"test.log.2024-10-03T00-00-00.000-0",
+		"other.log.2024-10-03T00-00-00.000",
+	}
+	backups := []string{
+		"test.log.2024-10-03T00-00-00.000-10",
+		"test.log.2024-10-03T00-00-00.000.gz",
+		"test.log.2024-10-03T00-00-00.000-9",
+	}
+	for _, name := range append(unrelated, backups...) {
+		err := os.WriteFile(filepath.Join(dir, name), nil, 0600)
+		if err != nil {
+			t.Fatalf("Unable to create %s: %v", name, err)
+		}
+	}
+
+	names, err := w.backups()
+	if err != nil {
+		t.Fatalf("Unable to list backups: %v", err)
+	}
+	expected := []string{
+		filepath.Join(dir, "test.log.2024-10-03T00-00-00.000"),
+		filepath.Join(dir, "test.log.2024-10-03T00-00-00.000-9"),
+		filepath.Join(dir, "test.log.2024-10-03T00-00-00.000-10"),
+	}
+	if strings.Join(names, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected backups %v, got %v", expected, names)
+	}
+
+	if err := w.removeOldBackups(); err != nil {
+		t.Fatalf("Unable to remove backups: %v", err)
+	}
+	for _, name := range unrelated {
+		if !exists(filepath.Join(dir, name)) {
+			t.Fatalf("Unrelated file %s was removed", name)
+		}
+	}
+	if !exists(expected[2]) {
+		t.Fatalf("Newest backup %s was removed", expected[2])
+	}
+}
@@ -0,0 +1,309 @@
+package btclog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/btcsuite/btclog"
+)
+
+// SamplingOption is the signature of a functional option that can be used to
+// modify the behaviour of a SamplingHandler.
+type SamplingOption func(*samplingOpts)
+
+// samplingBudget defines how many records of a single level from a single
+// call-site pass per interval.
+type samplingBudget struct {
+	// first is the number of records that pass at the start of each
+	// interval.
+	first uint64
+
+	// thereafter defines that every thereafter-th record passes once the
+	// first records of the interval have been used up. If it is zero, no
+	// further records pass.
+	thereafter uint64
+}
+
+// samplingOpts holds options that can be modified by a SamplingOption.
+type samplingOpts struct {
+	// interval is the duration after which the budget of each call-site
+	// is reset.
+	interval time.Duration
+
+	// budgets holds the budget of each sampled level. Records of levels
+	// without a budget always pass.
+	budgets map[slog.Level]samplingBudget
+
+	// now returns the current time.
+	now func() time.Time
+}
+
+// defaultSamplingOpts returns the default options of a SamplingHandler: the
+// first 100 records per second of each call-site pass for every level below
+// LevelError, followed by every 100th record.
+func defaultSamplingOpts() *samplingOpts {
+	budget := samplingBudget{first: 100, thereafter: 100}
+
+	return &samplingOpts{
+		interval: time.Second,
+		budgets: map[slog.Level]samplingBudget{
+			levelTrace: budget,
+			levelDebug: budget,
+			levelInfo:  budget,
+			levelWarn:  budget,
+		},
+		now: time.Now,
+	}
+}
+
+// WithSamplingInterval sets the interval after which the budget of each
+// call-site is reset. The default is one second.
+func WithSamplingInterval(interval time.Duration) SamplingOption {
+	return func(o *samplingOpts) {
+		o.interval = interval
+	}
+}
+
+// WithSamplingBudget sets the budget of the given level: the first records of
+// each call-site per interval pass, followed by every thereafter-th record. If
+// thereafter is zero, no more records of the call-site pass until the interval
+// ends. Budgets for LevelError and above are ignored since such records are
+// never sampled.
+func WithSamplingBudget(level btclog.Level, first,
+	thereafter uint64) SamplingOption {
+
+	return func(o *samplingOpts) {
+		o.budgets[toSlogLevel(level)] = samplingBudget{
+			first:      first,
+			thereafter: thereafter,
+		}
+	}
+}
+
+// WithoutSampling disables sampling for the given level so that all its
+// records pass.
+func WithoutSampling(level btclog.Level) SamplingOption {
+	return func(o *samplingOpts) {
+		delete(o.budgets, toSlogLevel(level))
+	}
+}
+
+// withSamplingClock sets the function used to get the current time.
+func withSamplingClock(now func() time.Time) SamplingOption {
+	return func(o *samplingOpts) {
+		o.now = now
+	}
+}
+
+// SamplingStats holds the number of records that passed a SamplingHandler and
+// that were dropped by it.
+type SamplingStats struct {
+	// Passed is the number of records that were passed on to the child
+	// Handler.
+	Passed uint64
+
+	// Dropped is the number of records that were sampled away.
+	Dropped uint64
+}
+
+// siteKey identifies the records of a single level from a single call-site.
+// The call-site is identified by the PC of the records.
+type siteKey struct {
+	pc    uintptr
+	level slog.Level
+}
+
+// siteCounter counts the records of a single siteKey in the current interval.
+type siteCounter struct {
+	// resetAt is the time, in Unix nanoseconds, at which the current
+	// interval ends.
+	resetAt atomic.Int64
+
+	// count is the number of records in the current interval.
+	count atomic.Uint64
+}
+
+// samplingState is shared by a SamplingHandler and all the Handlers derived
+// from it.
+type samplingState struct {
+	opts *samplingOpts
+
+	// sites maps each siteKey to its *siteCounter.
+	sites sync.Map
+
+	passed  atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// SamplingHandler is a Handler that limits the number of records that are
+// logged from a single call-site. For each level, the first N records of a
+// call-site per interval are passed on to the child Handler and after that
+// only every Mth record. Records of LevelError and above are never sampled.
+//
+// Call-sites are identified by the PC of the records, so records without a PC
+// share a single budget per level. The sampling decision doesn't take any
+// locks, so the SamplingHandler can be used in hot paths.
+type SamplingHandler struct {
+	handler Handler
+	state   *samplingState
+}
+
+// A compile-time check to ensure that SamplingHandler implements Handler.
+var _ Handler = (*SamplingHandler)(nil)
+
+// NewSamplingHandler creates a new SamplingHandler that passes the sampled
+// records on to the given Handler.
+func NewSamplingHandler(handler Handler,
+	options ...SamplingOption) *SamplingHandler {
+
+	opts := defaultSamplingOpts()
+	for _, o := range options {
+		o(opts)
+	}
+
+	return &SamplingHandler{
+		handler: handler,
+		state: &samplingState{
+			opts: opts,
+		},
+	}
+}
+
+// Level returns the current logging level of the child Handler.
+//
+// NOTE: This is part of the Handler interface.
+func (s *SamplingHandler) Level() btclog.Level {
+	return s.handler.Level()
+}
+
+// SetLevel changes the logging level of the child Handler to the passed
+// level.
+//
+// NOTE: This is part of the Handler interface.
+func (s *SamplingHandler) SetLevel(level btclog.Level) {
+	s.handler.SetLevel(level)
+}
+
+// Enabled reports whether the child Handler handles records at the given
+// level.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (s *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return s.handler.Enabled(ctx, level)
+}
+
+// Handle passes the Record on to the child Handler unless the budget of its
+// call-site has been used up.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (s *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !s.sample(r.Level, r.PC) {
+		s.state.dropped.Add(1)
+		return nil
+	}
+
+	s.state.passed.Add(1)
+
+	return s.handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a new Handler with the given attributes added.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (s *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h := s.handler.WithAttrs(attrs)
+	handler, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return s.with(handler)
+}
+
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (s *SamplingHandler) WithGroup(name string) slog.Handler {
+	h := s.handler.WithGroup(name)
+	handler, ok := h.(Handler)
+	if !ok {
+		return h
+	}
+
+	return s.with(handler)
+}
+
+// SubSystem returns a copy of the given handler but with the new tag. The
+// returned Handler shares the call-site budgets and stats of the receiver.
+//
+// NOTE: This is part of the Handler interface.
+func (s *SamplingHandler) SubSystem(tag string) Handler {
+	return s.with(s.handler.SubSystem(tag))
+}
+
+// Stats returns the number of records that have passed and that have been
+// dropped so far by the SamplingHandler and all Handlers derived from it.
+func (s *SamplingHandler) Stats() SamplingStats {
+	return SamplingStats{
+		Passed:  s.state.passed.Load(),
+		Dropped: s.state.dropped.Load(),
+	}
+}
+
+// with returns a new SamplingHandler that shares the state of the receiver
+// and wraps the given Handler.
+func (s *SamplingHandler) with(handler Handler) *SamplingHandler {
+	return &SamplingHandler{
+		handler: handler,
+		state:   s.state,
+	}
+}
+
+// sample returns true if a record of the given level from the call-site with
+// the given PC should pass.
+func (s *SamplingHandler) sample(level slog.Level, pc uintptr) bool {
+	opts := s.state.opts
+	if level >= levelError {
+		return true
+	}
+
+	budget, ok := opts.budgets[level]
+	if !ok {
+		return true
+	}
+
+	key := siteKey{pc: pc, level: level}
+	c, ok := s.state.sites.Load(key)
+	if !ok {
+		c, _ = s.state.sites.LoadOrStore(key, &siteCounter{})
+	}
+	counter := c.(*siteCounter)
+
+	// Start a new interval if the current one has ended. Only the caller
+	// that wins the race resets the count.
+	now := opts.now().UnixNano()
+	n := counter.count.Add(1)
+	resetAt := counter.resetAt.Load()
+	if now >= resetAt {
+		next := now + int64(opts.interval)
+		if counter.resetAt.CompareAndSwap(resetAt, next) {
+			counter.count.Store(1)
+			n = 1
+		}
+	}
+
+	switch {
+	case n <= budget.first:
+		return true
+
+	case budget.thereafter == 0:
+		return false
+
+	default:
+		return (n-budget.first)%budget.thereafter == 0
+	}
+}
@@ -0,0 +1,73 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSamplingHandler tests that records are sampled per call-site and level
+// and that error records are never sampled.
+func TestSamplingHandler(t *testing.T) {
+	t.Parallel()
+
+	var now atomic.Int64
+	clock := func() time.Time {
+		return time.Unix(0, now.Load())
+	}
+
+	var buf bytes.Buffer
+	sampler := NewSamplingHandler(
+		NewDefaultHandler(&buf, WithNoTimestamp()),
+		WithSamplingInterval(time.Second),
+		WithSamplingBudget(LevelInfo, 2, 3),
+		WithSamplingBudget(LevelError, 1, 0),
+		withSamplingClock(clock),
+	)
+	log := NewSLogger(sampler.SubSystem("PEER"))
+
+	// Records 1, 2, 5 and 8 of each call-site pass.
+	for i := 1; i <= 10; i++ {
+		log.Infof("a %d", i)
+		log.Infof("b %d", i)
+		log.ErrorS(context.Background(), "c", nil)
+	}
+
+	expected := map[string]int{
+		"[INF] PEER: a": 4, "[INF] PEER: b": 4, "[ERR] PEER: c": 10,
+	}
+	counts := make(map[string]int)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 {
+			counts[strings.Join(fields[:3], " ")]++
+		}
+	}
+	for prefix, count := range expected {
+		if counts[prefix] != count {
+			t.Fatalf("Expected %d lines of %q, got %d:\n%s", count,
+				prefix, counts[prefix], buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "a 8\n") {
+		t.Fatalf("Expected 8th record to pass:\n%s", buf.String())
+	}
+
+	stats := sampler.Stats()
+	if stats.Passed != 18 || stats.Dropped != 12 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+
+	// Once the interval has passed, the budget is reset.
+	now.Add(int64(time.Second))
+	buf.Reset()
+	for i := 1; i <= 3; i++ {
+		log.Infof("d %d", i)
+	}
+	if buf.String() != "[INF] PEER: d 1\n[INF] PEER: d 2\n" {
+		t.Fatalf("Unexpected output after reset: %q", buf.String())
+	}
+}
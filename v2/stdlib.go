@@ -0,0 +1,111 @@
+package btclog
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+
+	"github.com/btcsuite/btclog"
+)
+
+// stdWriter is an io.Writer that writes each line of a standard library
+// logger to a Logger.
+type stdWriter struct {
+	logger Logger
+	level  btclog.Level
+}
+
+// Write logs the given line at the level of its prefix, if it has one, and at
+// the default level of the writer otherwise.
+//
+// NOTE: This is part of the io.Writer interface.
+func (w *stdWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	level, msg := parseLevelPrefix(msg, w.level)
+
+	ctx := context.Background()
+	switch level {
+	case LevelTrace:
+		w.logger.TraceS(ctx, msg)
+	case LevelDebug:
+		w.logger.DebugS(ctx, msg)
+	case LevelInfo:
+		w.logger.InfoS(ctx, msg)
+	case LevelWarn:
+		w.logger.WarnS(ctx, msg, nil)
+	case LevelError:
+		w.logger.ErrorS(ctx, msg, nil)
+	case LevelCritical:
+		w.logger.CriticalS(ctx, msg, nil)
+	}
+
+	return len(p), nil
+}
+
+// parseLevelPrefix returns the level of the given message's prefix along with
+// the message without the prefix. Prefixes are level names or abbreviations,
+// as accepted by LevelFromString, in any case and either in brackets or
+// followed by a colon, e.g. "[WRN] ", "[error]" or "DEBUG: ". If the message
+// has no such prefix, it is returned as is along with the default level.
+func parseLevelPrefix(msg string, defaultLevel btclog.Level) (btclog.Level,
+	string) {
+
+	var word, rest string
+	switch {
+	case strings.HasPrefix(msg, "["):
+		end := strings.IndexByte(msg, ']')
+		if end < 0 {
+			return defaultLevel, msg
+		}
+		word, rest = msg[1:end], msg[end+1:]
+		rest = strings.TrimPrefix(rest, ":")
+
+	default:
+		end := strings.IndexByte(msg, ':')
+		if end < 0 {
+			return defaultLevel, msg
+		}
+		word, rest = msg[:end], msg[end+1:]
+	}
+
+	// LevelFromString also accepts "off", which isn't a level that a
+	// message can be logged at.
+	level, ok := LevelFromString(word)
+	if !ok || level == LevelOff {
+		return defaultLevel, msg
+	}
+
+	return level, strings.TrimLeft(rest, " ")
+}
+
+// NewStdLogger returns a standard library logger that writes each line to the
+// given Logger, so that the output of packages that use the log package ends up
+// in the same stream, with the sub-system tag of the Logger. Lines are logged
+// at the given level unless they start with a level prefix such as "[WRN] " or
+// "error: ", in which case the prefix is stripped and its level is used.
+// Since the returned logger adds frames to the call stack, the call-site that
+// is logged by handlers with the Lshortfile or Llongfile flag is not
+// meaningful.
+//
+// Usage:
+//
+//	srv := &http.Server{
+//		ErrorLog: log.NewStdLogger(rpcsLog, log.LevelWarn),
+//	}
+func NewStdLogger(logger Logger, level btclog.Level) *log.Logger {
+	return log.New(&stdWriter{logger: logger, level: level}, "", 0)
+}
+
+// SetSlogDefault installs the given Handler, with the given sub-system tag, as
+// the handler of the default slog.Logger. This way the records of packages
+// that log with the top-level functions of the slog package, or with
+// slog.Default, end up in the same stream as the btclog loggers and obey the
+// level of the Handler. As done by slog.SetDefault, the output of the log
+// package's default logger is redirected to the Handler as well, at
+// LevelInfo. Since the slog.Logger records the call-site on each record, as a
+// Logger does, the call-site logged by handlers with the Lshortfile or
+// Llongfile flag is that of the caller of the slog function.
+func SetSlogDefault(handler Handler, subsystem string) {
+	slog.SetDefault(slog.New(handler.SubSystem(subsystem)))
+}
@@ -0,0 +1,59 @@
+package btclog
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+// TestNewStdLogger tests that the lines of a standard library logger are
+// logged at the level of their prefix or at the default level.
+func TestNewStdLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	log := NewSLogger(
+		NewDefaultHandler(&buf, WithNoTimestamp()).SubSystem("HTTP"),
+	)
+	log.SetLevel(LevelDebug)
+
+	std := NewStdLogger(log, LevelInfo)
+	std.Print("http: TLS handshake error")
+	std.Print("[WRN] Slow request")
+	std.Printf("ERROR: request %d failed", 5)
+	std.Print("[trace] Dropped")
+	std.Print("debug:Headers parsed")
+	std.Print("[off] Not a level")
+
+	expected := `[INF] HTTP: http: TLS handshake error
+[WRN] HTTP: Slow request
+[ERR] HTTP: request 5 failed
+[DBG] HTTP: Headers parsed
+[INF] HTTP: [off] Not a level
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+// TestSetSlogDefault tests that the records of the default slog.Logger are
+// passed to the installed Handler with the correct call-site.
+func TestSetSlogDefault(t *testing.T) {
+	defaultLogger := slog.Default()
+	defer slog.SetDefault(defaultLogger)
+
+	var buf bytes.Buffer
+	handler := NewDefaultHandler(
+		&buf, WithNoTimestamp(), WithCallerFlags(Lshortfile),
+	)
+	handler.SetLevel(LevelInfo)
+	SetSlogDefault(handler, "LIB")
+
+	slog.Debug("Dropped")
+	slog.Warn("Deprecated option", "name", "foo")
+
+	expected := "[WRN] LIB stdlib_test.go:53: Deprecated option name=foo\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,169 @@
+package btclog
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+)
+
+const (
+	// TraceIDKey is the key of the trace ID attribute that is added by
+	// TraceParentExtractor.
+	TraceIDKey = "trace_id"
+
+	// SpanIDKey is the key of the span ID attribute that is added by
+	// TraceParentExtractor.
+	SpanIDKey = "span_id"
+
+	// traceParentLen is the length of a version 00 traceparent header.
+	traceParentLen = 55
+)
+
+// ErrInvalidTraceParent is returned by ParseTraceParent if the given value is
+// not a valid W3C traceparent header.
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+// TraceParent holds the fields of a W3C Trace Context traceparent header as
+// defined in https://www.w3.org/TR/trace-context/.
+type TraceParent struct {
+	// Version is the version of the header format.
+	Version byte
+
+	// TraceID identifies the whole trace.
+	TraceID [16]byte
+
+	// SpanID identifies the span of the caller, also known as the parent
+	// ID.
+	SpanID [8]byte
+
+	// Flags holds the trace flags, of which only the sampled flag is
+	// currently defined.
+	Flags byte
+}
+
+// ParseTraceParent parses the value of a traceparent header, for example
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". Headers of future
+// versions are accepted as long as they start with the fields defined by
+// version 00.
+func ParseTraceParent(s string) (TraceParent, error) {
+	var tp TraceParent
+
+	if len(s) < traceParentLen || s[2] != '-' || s[35] != '-' ||
+		s[52] != '-' {
+
+		return tp, ErrInvalidTraceParent
+	}
+
+	// Version 00 has no further fields, while later versions may only
+	// add fields after another dash.
+	if len(s) > traceParentLen && (s[:2] == "00" ||
+		s[traceParentLen] != '-') {
+
+		return tp, ErrInvalidTraceParent
+	}
+
+	var version, flags [1]byte
+	if !decodeLowerHex(version[:], s[:2]) ||
+		!decodeLowerHex(tp.TraceID[:], s[3:35]) ||
+		!decodeLowerHex(tp.SpanID[:], s[36:52]) ||
+		!decodeLowerHex(flags[:], s[53:55]) {
+
+		return tp, ErrInvalidTraceParent
+	}
+	tp.Version, tp.Flags = version[0], flags[0]
+
+	// Version ff and all-zero IDs are explicitly forbidden.
+	if tp.Version == 0xff || tp.TraceID == [16]byte{} ||
+		tp.SpanID == [8]byte{} {
+
+		return tp, ErrInvalidTraceParent
+	}
+
+	return tp, nil
+}
+
+// decodeLowerHex decodes the hex string s into dst. It returns false if s
+// doesn't have the expected length or isn't lowercase hex, as required by the
+// traceparent format.
+func decodeLowerHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+
+	return err == nil
+}
+
+// Sampled returns true if the sampled flag is set.
+func (t TraceParent) Sampled() bool {
+	return t.Flags&0x01 != 0
+}
+
+// TraceIDString returns the hex encoding of the trace ID.
+func (t TraceParent) TraceIDString() string {
+	return hex.EncodeToString(t.TraceID[:])
+}
+
+// SpanIDString returns the hex encoding of the span ID.
+func (t TraceParent) SpanIDString() string {
+	return hex.EncodeToString(t.SpanID[:])
+}
+
+// String returns the traceparent header value of the TraceParent. Only the
+// fields defined by version 00 are included.
+func (t TraceParent) String() string {
+	return hex.EncodeToString([]byte{t.Version}) + "-" +
+		t.TraceIDString() + "-" + t.SpanIDString() + "-" +
+		hex.EncodeToString([]byte{t.Flags})
+}
+
+type traceParentKey struct{}
+
+// WithTraceParent returns a copy of the context with which the given
+// TraceParent is associated. If the handler was created with
+// WithContextExtractor(TraceParentExtractor), all records that are logged with
+// the context get the trace and span IDs as attributes.
+//
+// Usage:
+//
+//	tp, err := log.ParseTraceParent(req.Header.Get("traceparent"))
+//	if err == nil {
+//		ctx = log.WithTraceParent(ctx, tp)
+//	}
+//	...
+//	log.InfoS(ctx, "Request handled") // trace_id=4bf9... span_id=00f0...
+func WithTraceParent(ctx context.Context, tp TraceParent) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, tp)
+}
+
+// TraceParentFromCtx returns the TraceParent associated with the context, if
+// any.
+func TraceParentFromCtx(ctx context.Context) (TraceParent, bool) {
+	tp, ok := ctx.Value(traceParentKey{}).(TraceParent)
+
+	return tp, ok
+}
+
+// TraceParentExtractor is a ContextExtractor that adds the trace and span IDs
+// of the TraceParent associated with the context via WithTraceParent. It can
+// be passed to a handler with WithContextExtractor.
+func TraceParentExtractor(ctx context.Context) []slog.Attr {
+	tp, ok := TraceParentFromCtx(ctx)
+	if !ok {
+		return nil
+	}
+
+	return []slog.Attr{
+		slog.String(TraceIDKey, tp.TraceIDString()),
+		slog.String(SpanIDKey, tp.SpanIDString()),
+	}
+}
@@ -0,0 +1,111 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+// TestParseTraceParent tests that valid traceparent headers are parsed and
+// that malformed ones are rejected.
+func TestParseTraceParent(t *testing.T) {
+	t.Parallel()
+
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tp, err := ParseTraceParent(valid)
+	if err != nil {
+		t.Fatalf("Unable to parse traceparent: %v", err)
+	}
+	if tp.String() != valid {
+		t.Fatalf("Expected %s, got %s", valid, tp.String())
+	}
+	if !tp.Sampled() {
+		t.Fatalf("Expected traceparent to be sampled")
+	}
+
+	// A future version may add fields.
+	_, err = ParseTraceParent("01" + valid[2:] + "-extra")
+	if err != nil {
+		t.Fatalf("Unable to parse future traceparent: %v", err)
+	}
+
+	invalid := []string{
+		"",
+		valid[:54],
+		valid + "-extra",
+		"01" + valid[2:] + "extra",
+		"ff" + valid[2:],
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	}
+	for _, s := range invalid {
+		if _, err := ParseTraceParent(s); err != ErrInvalidTraceParent {
+			t.Fatalf("Expected %q to be invalid, got %v", s, err)
+		}
+	}
+}
+
+// TestContextExtractor tests that attributes derived from the context are
+// added to the records of both the text and the JSON handler.
+func TestContextExtractor(t *testing.T) {
+	t.Parallel()
+
+	tp, err := ParseTraceParent(
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	)
+	if err != nil {
+		t.Fatalf("Unable to parse traceparent: %v", err)
+	}
+
+	type peerKey struct{}
+	peerExtractor := func(ctx context.Context) []slog.Attr {
+		if peer, ok := ctx.Value(peerKey{}).(string); ok {
+			return []slog.Attr{slog.String("peer", peer)}
+		}
+
+		return nil
+	}
+
+	var textBuf, jsonBuf bytes.Buffer
+	opts := []HandlerOption{
+		WithNoTimestamp(),
+		WithContextExtractor(TraceParentExtractor),
+		WithContextExtractor(peerExtractor),
+	}
+	log := NewSLogger(NewMultiHandler(
+		NewDefaultHandler(&textBuf, opts...),
+		NewJSONHandler(&jsonBuf, opts...),
+	).SubSystem("RPCS"))
+
+	ctx := context.Background()
+	log.InfoS(ctx, "No trace")
+
+	ctx = context.WithValue(ctx, peerKey{}, "alice")
+	ctx = WithCtx(WithTraceParent(ctx, tp), "req", 1)
+	log.InfoS(ctx, "Traced", "height", 100)
+
+	expectedText := `[INF] RPCS: No trace
+[INF] RPCS: Traced trace_id=4bf92f3577b34da6a3ce929d0e0e4736 ` +
+		`span_id=00f067aa0ba902b7 peer=alice req=1 height=100
+`
+	if textBuf.String() != expectedText {
+		t.Fatalf("Text result mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedText, textBuf.String())
+	}
+
+	expectedJSON := `{"level":"INF","subsystem":"RPCS","msg":"No trace"}
+{"level":"INF","subsystem":"RPCS","msg":"Traced",` +
+		`"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736",` +
+		`"span_id":"00f067aa0ba902b7","peer":"alice","req":1,` +
+		`"height":100}
+`
+	if jsonBuf.String() != expectedJSON {
+		t.Fatalf("JSON result mismatch. Expected \n\"%s\", got "+
+			"\n\"%s\"", expectedJSON, jsonBuf.String())
+	}
+}
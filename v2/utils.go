@@ -68,9 +68,25 @@ func writeTimestamp(buf *buffer, t time.Time) {
 }
 
 // callsite returns the file name and line number of the callsite to the
-// subsystem logger.
-func callsite(flag uint32, skipDepth int) (string, int) {
-	_, file, line, ok := runtime.Caller(skipDepth)
+// subsystem logger. If skipDepth is zero, it is taken from the given program
+// counter of a record. Otherwise, it is found by ascending the call stack,
+// where a depth of 6 is the caller of a Logger method. Since the depth
+// historically included two frames of the slog package, which records no
+// longer pass through, it is reduced by two.
+func callsite(flag uint32, skipDepth int, pc uintptr) (string, int) {
+	var (
+		file string
+		line int
+		ok   bool
+	)
+	switch {
+	case skipDepth > 0:
+		_, file, line, ok = runtime.Caller(skipDepth - 2)
+
+	case pc != 0:
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		file, line, ok = frame.File, frame.Line, frame.File != ""
+	}
 	if !ok {
 		return "???", 0
 	}
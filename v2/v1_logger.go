@@ -0,0 +1,147 @@
+package btclog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/btcsuite/btclog"
+)
+
+// V1Handler is a Handler that writes records to a v1 btclog.Logger, such as a
+// logger of a v1 Backend or btclog.Disabled. Any attributes are appended to the
+// message in the same key=value style as used by the DefaultHandler, so that
+// packages can move to the structured logging methods before the application
+// that uses them has moved to a v2 Handler.
+//
+// Since the v1 Logger determines its own call-site and timestamp, and already
+// has a sub-system tag, these are not taken from the record. Records are only
+// logged if they are at or above the level of the v1 Logger.
+//
+// NOTE: the call-site of the record is lost. A v1 Backend that was created
+// with the Lshortfile or Llongfile flag logs the location in this file, from
+// where the v1 Logger is called, rather than that of the caller.
+type V1Handler struct {
+	logger btclog.Logger
+
+	// attrs is used to keep track of the attributes and groups added with
+	// WithAttrs and WithGroup and to format them along with those of each
+	// record.
+	attrs *DefaultHandler
+}
+
+// A compile-time check to ensure that V1Handler implements Handler.
+var _ Handler = (*V1Handler)(nil)
+
+// NewV1Handler creates a new V1Handler that writes records to the given v1
+// Logger.
+func NewV1Handler(logger btclog.Logger) *V1Handler {
+	return &V1Handler{
+		logger: logger,
+		attrs:  NewDefaultHandler(io.Discard, WithNoTimestamp()),
+	}
+}
+
+// WrapV1Logger returns a Logger that writes to the given v1 Logger. The
+// structured logging methods of the returned Logger, including the attributes
+// that are associated with the context via WithCtx, can therefore be used with
+// a v1 Logger. If the given Logger already implements the v2 Logger interface,
+// it is returned as is. Call-sites are lost for a wrapped v1 Logger, as
+// explained for the V1Handler, so a v1 Backend with the Lshortfile or
+// Llongfile flag doesn't log the location of the caller.
+//
+// Usage:
+//
+//	func UseLogger(logger btclog.Logger) {
+//		log = btclogv2.WrapV1Logger(logger)
+//	}
+func WrapV1Logger(logger btclog.Logger) Logger {
+	if l, ok := logger.(Logger); ok {
+		return l
+	}
+
+	return NewSLogger(NewV1Handler(logger))
+}
+
+// Level returns the current logging level of the v1 Logger.
+//
+// NOTE: This is part of the Handler interface.
+func (v *V1Handler) Level() btclog.Level {
+	return v.logger.Level()
+}
+
+// SetLevel changes the logging level of the v1 Logger to the passed level.
+//
+// NOTE: This is part of the Handler interface.
+func (v *V1Handler) SetLevel(level btclog.Level) {
+	v.logger.SetLevel(level)
+}
+
+// Enabled reports whether the v1 Logger logs records at the given level.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (v *V1Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return fromSlogLevel(level) >= v.logger.Level()
+}
+
+// Handle writes the Record's message, followed by its attributes, to the v1
+// Logger at the Record's level.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (v *V1Handler) Handle(_ context.Context, r slog.Record) error {
+	buf := newBuffer()
+	defer buf.free()
+
+	buf.writeString(r.Message)
+	v.attrs.appendAttrs(buf, r)
+	msg := string(*buf)
+
+	switch fromSlogLevel(r.Level) {
+	case LevelTrace:
+		v.logger.Trace(msg)
+	case LevelDebug:
+		v.logger.Debug(msg)
+	case LevelInfo:
+		v.logger.Info(msg)
+	case LevelWarn:
+		v.logger.Warn(msg)
+	case LevelError:
+		v.logger.Error(msg)
+	case LevelCritical:
+		v.logger.Critical(msg)
+	}
+
+	return nil
+}
+
+// WithAttrs returns a new Handler with the given attributes added.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (v *V1Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &V1Handler{
+		logger: v.logger,
+		attrs:  v.attrs.WithAttrs(attrs).(*DefaultHandler),
+	}
+}
+
+// WithGroup returns a new Handler with the given group appended to the
+// receiver's existing groups.
+//
+// NOTE: this is part of the slog.Handler interface.
+func (v *V1Handler) WithGroup(name string) slog.Handler {
+	return &V1Handler{
+		logger: v.logger,
+		attrs:  v.attrs.WithGroup(name).(*DefaultHandler),
+	}
+}
+
+// SubSystem returns a copy of the handler. The tag is ignored since the v1
+// Logger already has a sub-system tag that can't be changed.
+//
+// NOTE: This is part of the Handler interface.
+func (v *V1Handler) SubSystem(_ string) Handler {
+	return &V1Handler{
+		logger: v.logger,
+		attrs:  v.attrs,
+	}
+}
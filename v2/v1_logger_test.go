@@ -0,0 +1,82 @@
+package btclog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btclog"
+)
+
+// TestWrapV1Logger tests that structured logs written through a wrapped v1
+// Logger are formatted in the key=value style and obey the v1 Logger's level.
+func TestWrapV1Logger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	v1Logger := btclog.NewBackend(&buf).Logger("PEER")
+	v1Logger.SetLevel(btclog.LevelDebug)
+
+	log := WrapV1Logger(v1Logger)
+	ctx := WithCtx(context.Background(), "peer", "127.0.0.1:8333")
+
+	log.TraceS(ctx, "Dropped")
+	log.DebugS(ctx, "Received message", "cmd", "inv",
+		slog.Group("inv", "count", 2))
+	log.ErrorS(ctx, "Disconnecting", errors.New("timeout"))
+	log.Infof("Connected to %d peers", 8)
+
+	grouped := NewSLogger(
+		NewV1Handler(v1Logger).WithGroup("req").(Handler),
+	)
+	grouped.WarnS(context.Background(), "Slow", nil, "ms", 120)
+
+	expected := []string{
+		`[DBG] PEER: Received message peer=127.0.0.1:8333 cmd=inv ` +
+			`inv.count=2`,
+		`[ERR] PEER: Disconnecting peer=127.0.0.1:8333 err=timeout`,
+		`[INF] PEER: Connected to 8 peers`,
+		`[WRN] PEER: Slow req.ms=120`,
+	}
+
+	// Strip the timestamps that the v1 Backend always writes.
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got:\n%s", len(expected),
+			buf.String())
+	}
+	for i, line := range lines {
+		_, line, _ = strings.Cut(line, " [")
+		if "["+line != expected[i] {
+			t.Fatalf("Expected %q, got %q", expected[i], "["+line)
+		}
+	}
+
+	// A v2 Logger is returned as is.
+	if WrapV1Logger(log) != log {
+		t.Fatalf("Expected v2 Logger to be returned as is")
+	}
+}
+
+// TestWrapV1LoggerCallSite tests that the call-site logged by a v1 Backend with
+// the Lshortfile flag is that of the V1Handler rather than the caller's, as
+// documented.
+func TestWrapV1LoggerCallSite(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	backend := btclog.NewBackend(&buf, btclog.WithFlags(btclog.Lshortfile))
+	log := WrapV1Logger(backend.Logger("PEER"))
+
+	log.InfoS(context.Background(), "hi", "k", 1)
+
+	_, line, _ := strings.Cut(buf.String(), " [")
+	if !strings.HasPrefix(line, "INF] PEER v1_logger.go:") ||
+		!strings.HasSuffix(line, ": hi k=1\n") {
+
+		t.Fatalf("Unexpected call-site: %q", buf.String())
+	}
+}